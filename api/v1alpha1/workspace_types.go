@@ -0,0 +1,119 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+
+// Package v1alpha1 contains the Workspace CRD types the controller
+// reconciles: what compute to provision, what to run on it, and the status
+// observed back from the cluster.
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/kaito-project/kaito/pkg/utils/registry"
+)
+
+// ModelImageAccessMode controls whether a preset's inference image is
+// fetched from kaito's public mirror or from a private image the caller
+// supplies directly.
+type ModelImageAccessMode string
+
+const (
+	ModelImageAccessModePublic  ModelImageAccessMode = "public"
+	ModelImageAccessModePrivate ModelImageAccessMode = "private"
+)
+
+// ResourceSpec describes the compute a Workspace should provision.
+type ResourceSpec struct {
+	// InstanceType is the VM SKU to provision, e.g. "Standard_NC96ads_A100_v4".
+	InstanceType string `json:"instanceType,omitempty"`
+
+	// Count is the number of single-node replicas to provision. When
+	// LeaderCount is set, LeaderCount decides the number of leader/worker
+	// replica groups instead; Count is still read for every workspace and
+	// must be set.
+	Count *int32 `json:"count,omitempty"`
+
+	// LeaderCount is the number of leader/worker replica groups to
+	// provision, e.g. 4 independently-serving replicas. Takes precedence
+	// over Count when set.
+	LeaderCount *int32 `json:"leaderCount,omitempty"`
+
+	// WorkersPerLeader is how many worker nodes accompany each leader in a
+	// replica group, e.g. 1 for a model split across 2 nodes total. Zero
+	// (the default) means single-node replicas.
+	WorkersPerLeader *int32 `json:"workersPerLeader,omitempty"`
+
+	// LabelSelector further constrains which nodes the workspace may use.
+	LabelSelector *metav1.LabelSelector `json:"labelSelector,omitempty"`
+}
+
+// PresetOptions configures a privately-hosted preset image.
+type PresetOptions struct {
+	// Image is the full image reference to pull when AccessMode is private.
+	Image string `json:"image,omitempty"`
+
+	// ImagePullSecrets names Secrets in the workspace's namespace used to
+	// pull Image.
+	ImagePullSecrets []string `json:"imagePullSecrets,omitempty"`
+}
+
+// PresetSpec identifies the model preset to serve.
+type PresetSpec struct {
+	// Name is the preset's identifier, e.g. "llama-2-7b".
+	Name string `json:"name"`
+
+	// AccessMode selects whether Image comes from kaito's public preset
+	// mirror or from PresetOptions.Image.
+	AccessMode ModelImageAccessMode `json:"accessMode,omitempty"`
+
+	PresetOptions PresetOptions `json:"presetOptions,omitempty"`
+}
+
+// AdapterSource describes where to fetch a LoRA adapter image from.
+type AdapterSource struct {
+	Image            string   `json:"image,omitempty"`
+	ImagePullSecrets []string `json:"imagePullSecrets,omitempty"`
+}
+
+// AdapterSpec is one LoRA adapter to load alongside the base preset.
+type AdapterSpec struct {
+	Source AdapterSource `json:"source,omitempty"`
+}
+
+// InferenceSpec describes what to serve and how.
+type InferenceSpec struct {
+	Preset   PresetSpec    `json:"preset"`
+	Adapters []AdapterSpec `json:"adapters,omitempty"`
+
+	// Runtime selects which serving stack (model.RuntimeName) launches the
+	// preset, e.g. "vllm" or "tgi". Defaults to the preset's own runtime
+	// (usually torchrun) when empty.
+	Runtime string `json:"runtime,omitempty"`
+}
+
+// WorkspaceStatus is the observed state of a Workspace.
+type WorkspaceStatus struct {
+	// WorkerNodes lists the nodes kaito has provisioned or selected for
+	// this workspace.
+	WorkerNodes []string `json:"workerNodes,omitempty"`
+}
+
+// Workspace is the top-level CRD a user creates to request inference (or
+// tuning) capacity and have kaito provision and run it.
+type Workspace struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Resource  ResourceSpec  `json:"resource,omitempty"`
+	Inference InferenceSpec `json:"inference,omitempty"`
+
+	// RegistryCredentials auto-provisions image pull secrets for private
+	// preset/adapter registries (ACR, ECR, GCR, or plain username/password),
+	// in addition to any Secrets named directly on Inference.Preset or
+	// Inference.Adapters. Each entry references the Secret holding the
+	// actual credential material rather than carrying it inline, so it
+	// never appears in the Workspace spec itself.
+	RegistryCredentials []registry.CredentialRef `json:"registryCredentials,omitempty"`
+
+	Status WorkspaceStatus `json:"status,omitempty"`
+}
@@ -0,0 +1,83 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+
+// Package inference provides the pluggable serving-stack abstraction used by
+// the workspace inference controller: a preset registers parameters once and
+// picks the Runtime that actually launches it (torchrun, vLLM, TGI, ...).
+package inference
+
+import (
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/kaito-project/kaito/pkg/model"
+)
+
+// Params is the set of inputs a Runtime needs to build a container command
+// and resource footprint for a single preset instance.
+type Params struct {
+	Preset       *model.PresetParam
+	SKUNumGPUs   string
+	NNodes       int
+	NProcPerNode int
+}
+
+// Runtime builds the container command, port, probes, env vars, and volumes
+// for a given preset. Each serving stack (torchrun, vLLM, TGI) implements
+// this once; `PresetParam.Runtime` selects which implementation is used.
+type Runtime interface {
+	// Name reports the RuntimeName this implementation serves.
+	Name() model.RuntimeName
+
+	// BuildCommand returns the full container command line.
+	BuildCommand(p Params) []string
+
+	// ContainerPort returns the port the runtime's server listens on.
+	ContainerPort() int32
+
+	// LivenessProbe and ReadinessProbe return the probes used to determine
+	// whether the runtime's server process is alive and actually serving.
+	LivenessProbe() *corev1.Probe
+	ReadinessProbe() *corev1.Probe
+
+	// Volumes returns any extra volumes/mounts the runtime requires beyond
+	// the shared-memory and adapter volumes common to every preset.
+	Volumes() ([]corev1.Volume, []corev1.VolumeMount)
+}
+
+// withDefaults returns a copy of overrides with every key in defaults that
+// overrides does not already set added to it, so a Runtime can derive flags
+// like --tensor-parallel-size from Params.NProcPerNode while still letting an
+// explicit PresetParam.RuntimeParams entry win.
+func withDefaults(overrides map[string]string, defaults map[string]string) map[string]string {
+	merged := make(map[string]string, len(overrides)+len(defaults))
+	for k, v := range defaults {
+		merged[k] = v
+	}
+	for k, v := range overrides {
+		merged[k] = v
+	}
+	return merged
+}
+
+// registry maps a RuntimeName to its Runtime implementation.
+var registry = map[model.RuntimeName]Runtime{}
+
+func register(r Runtime) {
+	registry[r.Name()] = r
+}
+
+// Get returns the Runtime registered for name, falling back to the torchrun
+// runtime when name is empty so existing presets keep working unmodified.
+func Get(name model.RuntimeName) (Runtime, bool) {
+	if name == "" {
+		name = model.RuntimeNameTorchrun
+	}
+	r, ok := registry[name]
+	return r, ok
+}
+
+func init() {
+	register(&torchrunRuntime{})
+	register(&vllmRuntime{})
+	register(&tgiRuntime{})
+}
@@ -0,0 +1,71 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package inference
+
+import (
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"github.com/kaito-project/kaito/pkg/model"
+	"github.com/kaito-project/kaito/pkg/utils"
+)
+
+const (
+	tgiPort       = 80
+	tgiProbePath  = "/health"
+	tgiEntrypoint = "text-generation-launcher"
+)
+
+// tgiRuntime launches Hugging Face's Text Generation Inference server.
+// Serving options (--num-shard, --max-input-length, ...) come from
+// PresetParam.RuntimeParams[model.RuntimeNameTGI].
+type tgiRuntime struct{}
+
+func (t *tgiRuntime) Name() model.RuntimeName { return model.RuntimeNameTGI }
+
+func (t *tgiRuntime) BuildCommand(p Params) []string {
+	// --num-shard defaults to the actual per-node GPU count computed for
+	// this workspace; an explicit RuntimeParams entry still wins.
+	defaults := map[string]string{}
+	if p.NProcPerNode > 0 {
+		defaults["num-shard"] = strconv.Itoa(p.NProcPerNode)
+	}
+	tgiParams := withDefaults(p.Preset.RuntimeParams[model.RuntimeNameTGI], defaults)
+
+	serverCommand := utils.BuildCmdStr(tgiEntrypoint, tgiParams)
+	return utils.ShellCmd(serverCommand)
+}
+
+func (t *tgiRuntime) ContainerPort() int32 { return tgiPort }
+
+func (t *tgiRuntime) LivenessProbe() *corev1.Probe {
+	return &corev1.Probe{
+		ProbeHandler: corev1.ProbeHandler{
+			HTTPGet: &corev1.HTTPGetAction{
+				Port: intstr.FromInt(tgiPort),
+				Path: tgiProbePath,
+			},
+		},
+		InitialDelaySeconds: 600, // 10 minutes
+		PeriodSeconds:       10,
+	}
+}
+
+func (t *tgiRuntime) ReadinessProbe() *corev1.Probe {
+	return &corev1.Probe{
+		ProbeHandler: corev1.ProbeHandler{
+			HTTPGet: &corev1.HTTPGetAction{
+				Port: intstr.FromInt(tgiPort),
+				Path: tgiProbePath,
+			},
+		},
+		InitialDelaySeconds: 30,
+		PeriodSeconds:       10,
+	}
+}
+
+func (t *tgiRuntime) Volumes() ([]corev1.Volume, []corev1.VolumeMount) {
+	return nil, nil
+}
@@ -0,0 +1,79 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package inference
+
+import (
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"github.com/kaito-project/kaito/pkg/model"
+	"github.com/kaito-project/kaito/pkg/utils"
+)
+
+const (
+	torchrunProbePath     = "/health"
+	torchrunPort          = 5000
+	torchrunInferenceFile = "inference_api.py"
+)
+
+// torchrunRuntime launches a preset's reference inference server via
+// `torchrun <TORCH_PARAMS> <OPTIONAL_RDZV_PARAMS> inference_api.py
+// <MODEL_PARAMS>`. It is the default runtime for presets that do not set
+// PresetParam.Runtime.
+type torchrunRuntime struct{}
+
+func (t *torchrunRuntime) Name() model.RuntimeName { return model.RuntimeNameTorchrun }
+
+func (t *torchrunRuntime) BuildCommand(p Params) []string {
+	// nnodes/nproc_per_node reflect the actual SKU's GPU count and node
+	// layout computed for this workspace; an explicit TorchRunParams entry
+	// (e.g. updateTorchParamsForDistributedInference's LWS_GROUP_SIZE
+	// expression) still wins over these computed defaults.
+	defaults := map[string]string{}
+	if p.NNodes > 0 {
+		defaults["nnodes"] = strconv.Itoa(p.NNodes)
+	}
+	if p.NProcPerNode > 0 {
+		defaults["nproc_per_node"] = strconv.Itoa(p.NProcPerNode)
+	}
+	torchRunParams := withDefaults(p.Preset.TorchRunParams, defaults)
+
+	torchCommand := utils.BuildCmdStr(p.Preset.BaseCommand, torchRunParams)
+	torchCommand = utils.BuildCmdStr(torchCommand, p.Preset.TorchRunRdzvParams)
+	modelCommand := utils.BuildCmdStr(torchrunInferenceFile, p.Preset.ModelRunParams)
+	return utils.ShellCmd(torchCommand + " " + modelCommand)
+}
+
+func (t *torchrunRuntime) ContainerPort() int32 { return torchrunPort }
+
+func (t *torchrunRuntime) LivenessProbe() *corev1.Probe {
+	return &corev1.Probe{
+		ProbeHandler: corev1.ProbeHandler{
+			HTTPGet: &corev1.HTTPGetAction{
+				Port: intstr.FromInt(torchrunPort),
+				Path: torchrunProbePath,
+			},
+		},
+		InitialDelaySeconds: 600, // 10 minutes
+		PeriodSeconds:       10,
+	}
+}
+
+func (t *torchrunRuntime) ReadinessProbe() *corev1.Probe {
+	return &corev1.Probe{
+		ProbeHandler: corev1.ProbeHandler{
+			HTTPGet: &corev1.HTTPGetAction{
+				Port: intstr.FromInt(torchrunPort),
+				Path: torchrunProbePath,
+			},
+		},
+		InitialDelaySeconds: 30,
+		PeriodSeconds:       10,
+	}
+}
+
+func (t *torchrunRuntime) Volumes() ([]corev1.Volume, []corev1.VolumeMount) {
+	return nil, nil
+}
@@ -0,0 +1,77 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package inference
+
+import (
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"github.com/kaito-project/kaito/pkg/model"
+	"github.com/kaito-project/kaito/pkg/utils"
+)
+
+const (
+	vllmPort          = 8000
+	vllmLivenessPath  = "/health"
+	vllmReadinessPath = "/v1/models"
+	vllmServerModule  = "python -m vllm.entrypoints.openai.api_server"
+)
+
+// vllmRuntime launches an OpenAI-compatible vLLM server. Parallelism and
+// serving options (--tensor-parallel-size, --pipeline-parallel-size,
+// --max-model-len, --served-model-name, ...) come from
+// PresetParam.RuntimeParams[model.RuntimeNameVLLM].
+type vllmRuntime struct{}
+
+func (v *vllmRuntime) Name() model.RuntimeName { return model.RuntimeNameVLLM }
+
+func (v *vllmRuntime) BuildCommand(p Params) []string {
+	// --tensor-parallel-size/--pipeline-parallel-size default to the actual
+	// per-node GPU count and node count computed for this workspace; an
+	// explicit RuntimeParams entry still wins over these computed defaults.
+	defaults := map[string]string{}
+	if p.NProcPerNode > 0 {
+		defaults["tensor-parallel-size"] = strconv.Itoa(p.NProcPerNode)
+	}
+	if p.NNodes > 0 {
+		defaults["pipeline-parallel-size"] = strconv.Itoa(p.NNodes)
+	}
+	vllmParams := withDefaults(p.Preset.RuntimeParams[model.RuntimeNameVLLM], defaults)
+
+	serverCommand := utils.BuildCmdStr(vllmServerModule, vllmParams)
+	return utils.ShellCmd(serverCommand)
+}
+
+func (v *vllmRuntime) ContainerPort() int32 { return vllmPort }
+
+func (v *vllmRuntime) LivenessProbe() *corev1.Probe {
+	return &corev1.Probe{
+		ProbeHandler: corev1.ProbeHandler{
+			HTTPGet: &corev1.HTTPGetAction{
+				Port: intstr.FromInt(vllmPort),
+				Path: vllmLivenessPath,
+			},
+		},
+		InitialDelaySeconds: 600, // 10 minutes
+		PeriodSeconds:       10,
+	}
+}
+
+func (v *vllmRuntime) ReadinessProbe() *corev1.Probe {
+	return &corev1.Probe{
+		ProbeHandler: corev1.ProbeHandler{
+			HTTPGet: &corev1.HTTPGetAction{
+				Port: intstr.FromInt(vllmPort),
+				Path: vllmReadinessPath,
+			},
+		},
+		InitialDelaySeconds: 30,
+		PeriodSeconds:       10,
+	}
+}
+
+func (v *vllmRuntime) Volumes() ([]corev1.Volume, []corev1.VolumeMount) {
+	return nil, nil
+}
@@ -0,0 +1,47 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package model
+
+import "time"
+
+// RuntimeName identifies a pluggable serving stack a preset can be launched
+// with, e.g. the torchrun-based reference server or an OpenAI-compatible
+// server such as vLLM or TGI.
+type RuntimeName string
+
+const (
+	RuntimeNameTorchrun RuntimeName = "torchrun"
+	RuntimeNameVLLM     RuntimeName = "vllm"
+	RuntimeNameTGI      RuntimeName = "tgi"
+)
+
+// PresetParam captures everything needed to construct the serving command,
+// resource requirements, and readiness behavior for a given preset model.
+type PresetParam struct {
+	Tag                 string
+	GPUCountRequirement string
+	ReadinessTimeout    time.Duration
+
+	// BaseCommand is the entrypoint prefixed to the runtime-specific
+	// command line, e.g. "python3".
+	BaseCommand string
+
+	// TorchRunParams, TorchRunRdzvParams, and ModelRunParams configure the
+	// torchrun runtime. Only the torchrun runtime reads them, i.e. when the
+	// runtime selected for the workspace (its spec.inference.runtime, else
+	// Runtime) is empty or RuntimeNameTorchrun.
+	TorchRunParams     map[string]string
+	TorchRunRdzvParams map[string]string
+	ModelRunParams     map[string]string
+
+	WorldSize int
+
+	// Runtime selects which serving stack builds the container command for
+	// this preset. Defaults to RuntimeNameTorchrun when empty.
+	Runtime RuntimeName
+
+	// RuntimeParams holds runtime-specific parameters keyed by runtime name
+	// (e.g. vLLM's --tensor-parallel-size), so a single preset registration
+	// can carry parameters for every runtime it supports.
+	RuntimeParams map[RuntimeName]map[string]string
+}
@@ -4,34 +4,56 @@ package resources
 
 import (
 	"context"
-	"fmt"
 	"time"
 
 	appsv1 "k8s.io/api/apps/v1"
-	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/client-go/util/retry"
 	"k8s.io/klog/v2"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/kaito-project/kaito/pkg/resources/statuscheck"
 )
 
+// fieldManager is the stable field manager kaito uses for every
+// server-side-apply call, so repeated applies of the same generated
+// Deployment/StatefulSet/Service are recognized as the same owner instead of
+// fighting over field ownership with each other.
+const fieldManager = "kaito-workspace"
+
+// isRetriableAPIError reports whether err is a transient API server problem
+// worth retrying, as opposed to e.g. AlreadyExists (which server-side apply
+// makes moot) or a validation error that will never succeed on retry.
+func isRetriableAPIError(err error) bool {
+	return apierrors.IsServerTimeout(err) || apierrors.IsTooManyRequests(err) || apierrors.IsInternalError(err)
+}
+
+// CreateResource applies resource via server-side apply, so calling it
+// repeatedly with the same generated object (e.g. on every reconcile) both
+// creates it the first time and reconciles away any spec drift afterward,
+// instead of erroring with AlreadyExists.
 func CreateResource(ctx context.Context, resource client.Object, kubeClient client.Client) error {
 	switch r := resource.(type) {
 	case *appsv1.Deployment:
 		klog.InfoS("CreateDeployment", "deployment", klog.KObj(r))
+		r.SetGroupVersionKind(appsv1.SchemeGroupVersion.WithKind("Deployment"))
 	case *appsv1.StatefulSet:
 		klog.InfoS("CreateStatefulSet", "statefulset", klog.KObj(r))
+		r.SetGroupVersionKind(appsv1.SchemeGroupVersion.WithKind("StatefulSet"))
 	case *corev1.Service:
 		klog.InfoS("CreateService", "service", klog.KObj(r))
+		r.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("Service"))
 	case *corev1.ConfigMap:
 		klog.InfoS("CreateConfigMap", "configmap", klog.KObj(r))
+		r.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("ConfigMap"))
 	}
 
-	// Create the resource.
-	return retry.OnError(retry.DefaultBackoff, func(err error) bool {
-		return true
-	}, func() error {
-		return kubeClient.Create(ctx, resource, &client.CreateOptions{})
+	// Server-side apply marshals resource's Go struct directly rather than
+	// going through a scheme-aware encoder, so apiVersion/kind must be set
+	// on the object explicitly above or the apiserver rejects the patch.
+	return retry.OnError(retry.DefaultBackoff, isRetriableAPIError, func() error {
+		return kubeClient.Patch(ctx, resource, client.Apply, client.ForceOwnership, client.FieldOwner(fieldManager))
 	})
 }
 
@@ -45,48 +67,13 @@ func GetResource(ctx context.Context, name, namespace string, kubeClient client.
 	return err
 }
 
+// CheckResourceStatus waits until obj (and anything it owns, e.g. a
+// StatefulSet's Pods/PVCs/headless Service) is ready, or returns a
+// *statuscheck.NotReadyError describing what still isn't once
+// timeoutDuration elapses.
 func CheckResourceStatus(obj client.Object, kubeClient client.Client, timeoutDuration time.Duration) error {
-	// Use Context for timeout
 	ctx, cancel := context.WithTimeout(context.Background(), timeoutDuration)
 	defer cancel()
 
-	ticker := time.NewTicker(1 * time.Second)
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-
-		case <-ticker.C:
-			key := client.ObjectKey{
-				Name:      obj.GetName(),
-				Namespace: obj.GetNamespace(),
-			}
-			err := kubeClient.Get(ctx, key, obj)
-			if err != nil {
-				return err
-			}
-
-			switch k8sResource := obj.(type) {
-			case *appsv1.Deployment:
-				if k8sResource.Status.ReadyReplicas == *k8sResource.Spec.Replicas {
-					klog.InfoS("deployment status is ready", "deployment", k8sResource.Name)
-					return nil
-				}
-			case *appsv1.StatefulSet:
-				if k8sResource.Status.ReadyReplicas == *k8sResource.Spec.Replicas {
-					klog.InfoS("statefulset status is ready", "statefulset", k8sResource.Name)
-					return nil
-				}
-			case *batchv1.Job:
-				klog.InfoS("checking job status", "name", k8sResource.Name, "namespace", k8sResource.Namespace, "succeeded", k8sResource.Status.Succeeded, "active", k8sResource.Status.Active, "failed", k8sResource.Status.Failed)
-				if k8sResource.Status.Failed == 0 {
-					return nil
-				}
-			default:
-				return fmt.Errorf("unsupported resource type")
-			}
-		}
-	}
+	return statuscheck.Wait(ctx, obj, kubeClient)
 }
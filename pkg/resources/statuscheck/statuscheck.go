@@ -0,0 +1,337 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+
+// Package statuscheck implements a kstatus-style readiness engine, modeled on
+// Helm 3.5's resource-status implementation, that knows how to decide whether
+// a Kubernetes object (and the objects it owns) has actually converged rather
+// than merely been created.
+package statuscheck
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// pollInterval is how frequently Wait polls the API server while waiting for
+// a resource (and its owned sub-resources) to become ready.
+const pollInterval = 1 * time.Second
+
+// NotReadyError is returned when one or more sub-resources of the object
+// under inspection are not yet ready. Callers can use it to surface
+// actionable status conditions instead of a bare timeout error.
+type NotReadyError struct {
+	// Resources holds a human-readable description of each sub-resource
+	// that is not ready, e.g. "pod llama-0: condition Ready is False".
+	Resources []string
+}
+
+func (e *NotReadyError) Error() string {
+	return fmt.Sprintf("resource(s) not ready: %s", strings.Join(e.Resources, "; "))
+}
+
+// Wait polls obj (and any sub-resources it owns) until every one of them is
+// ready, ctx is cancelled, or an unrecoverable error occurs. On timeout it
+// returns a *NotReadyError listing which sub-resources never became ready so
+// the caller can turn that into an actionable status condition.
+func Wait(ctx context.Context, obj client.Object, kubeClient client.Client) error {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	var lastErr error
+	for {
+		select {
+		case <-ctx.Done():
+			if lastErr != nil {
+				return lastErr
+			}
+			return ctx.Err()
+		case <-ticker.C:
+			key := client.ObjectKey{Name: obj.GetName(), Namespace: obj.GetNamespace()}
+			if err := kubeClient.Get(ctx, key, obj); err != nil {
+				return err
+			}
+
+			notReady, err := notReadyResources(ctx, obj, kubeClient)
+			if err != nil {
+				return err
+			}
+			if len(notReady) == 0 {
+				klog.InfoS("resource status is ready", "kind", obj.GetObjectKind().GroupVersionKind().Kind, "name", obj.GetName())
+				return nil
+			}
+			lastErr = &NotReadyError{Resources: notReady}
+		}
+	}
+}
+
+// notReadyResources returns a description of every sub-resource of obj that
+// is not yet ready. An empty, nil slice means obj is fully ready.
+func notReadyResources(ctx context.Context, obj client.Object, kubeClient client.Client) ([]string, error) {
+	switch o := obj.(type) {
+	case *appsv1.Deployment:
+		return deploymentNotReady(o), nil
+	case *appsv1.StatefulSet:
+		notReady := statefulSetNotReady(o)
+		owned, err := ownedObjectsNotReady(ctx, o, kubeClient)
+		if err != nil {
+			return nil, err
+		}
+		return append(notReady, owned...), nil
+	case *batchv1.Job:
+		return jobNotReady(o)
+	case *corev1.Pod:
+		return podNotReady(o), nil
+	case *corev1.PersistentVolumeClaim:
+		return pvcNotReady(o), nil
+	case *corev1.Service:
+		return serviceNotReady(o), nil
+	default:
+		return nil, fmt.Errorf("unsupported resource type %T", obj)
+	}
+}
+
+func deploymentNotReady(d *appsv1.Deployment) []string {
+	var reasons []string
+	if d.Status.ObservedGeneration < d.Generation {
+		reasons = append(reasons, fmt.Sprintf("deployment %s: observed generation %d is behind generation %d", d.Name, d.Status.ObservedGeneration, d.Generation))
+		return reasons
+	}
+	if d.Spec.Replicas != nil && d.Status.UpdatedReplicas < *d.Spec.Replicas {
+		reasons = append(reasons, fmt.Sprintf("deployment %s: %d out of %d new replicas updated", d.Name, d.Status.UpdatedReplicas, *d.Spec.Replicas))
+	}
+
+	maxUnavailable := 0
+	if d.Spec.Replicas != nil && d.Spec.Strategy.RollingUpdate != nil && d.Spec.Strategy.RollingUpdate.MaxUnavailable != nil {
+		// MaxUnavailable is commonly expressed as a percentage (Kubernetes
+		// defaults it to "25%"); IntValue() silently truncates that to 0,
+		// which would wrongly demand 100% availability. Scale it the same
+		// way Helm's resource-status check does -- rounding down, same as
+		// the deployment controller itself, so a fractional percentage (e.g.
+		// 10% of 1 replica) never tolerates more unavailability than the
+		// user actually asked for. Rounding direction matters here: maxSurge
+		// rounds up, maxUnavailable rounds down, on purpose.
+		maxUnavailable, _ = intstr.GetScaledValueFromIntOrPercent(d.Spec.Strategy.RollingUpdate.MaxUnavailable, int(*d.Spec.Replicas), false)
+	}
+	if d.Spec.Replicas != nil {
+		minAvailable := *d.Spec.Replicas - int32(maxUnavailable)
+		if d.Status.AvailableReplicas < minAvailable {
+			reasons = append(reasons, fmt.Sprintf("deployment %s: %d of %d (minimum) replicas available", d.Name, d.Status.AvailableReplicas, minAvailable))
+		}
+	}
+	return reasons
+}
+
+func statefulSetNotReady(s *appsv1.StatefulSet) []string {
+	var reasons []string
+	if s.Status.ObservedGeneration < s.Generation {
+		reasons = append(reasons, fmt.Sprintf("statefulset %s: observed generation %d is behind generation %d", s.Name, s.Status.ObservedGeneration, s.Generation))
+		return reasons
+	}
+	if s.Status.CurrentRevision != s.Status.UpdateRevision {
+		reasons = append(reasons, fmt.Sprintf("statefulset %s: current revision %s does not match update revision %s", s.Name, s.Status.CurrentRevision, s.Status.UpdateRevision))
+	}
+	if s.Spec.Replicas != nil && s.Status.ReadyReplicas < *s.Spec.Replicas {
+		reasons = append(reasons, fmt.Sprintf("statefulset %s: %d of %d replicas ready", s.Name, s.Status.ReadyReplicas, *s.Spec.Replicas))
+	}
+	return reasons
+}
+
+func jobNotReady(j *batchv1.Job) ([]string, error) {
+	for _, cond := range j.Status.Conditions {
+		if cond.Type == batchv1.JobFailed && cond.Status == corev1.ConditionTrue {
+			return nil, fmt.Errorf("job %s failed: %s", j.Name, cond.Message)
+		}
+		if cond.Type == batchv1.JobComplete && cond.Status == corev1.ConditionTrue {
+			return nil, nil
+		}
+	}
+	completions := int32(1)
+	if j.Spec.Completions != nil {
+		completions = *j.Spec.Completions
+	}
+	if j.Status.Succeeded < completions {
+		return []string{fmt.Sprintf("job %s: %d of %d completions succeeded", j.Name, j.Status.Succeeded, completions)}, nil
+	}
+	return nil, nil
+}
+
+func podNotReady(p *corev1.Pod) []string {
+	if cs := crashLoopingContainer(p); cs != "" {
+		return []string{fmt.Sprintf("pod %s: container %s is in CrashLoopBackOff", p.Name, cs)}
+	}
+	for _, cond := range p.Status.Conditions {
+		if cond.Type == corev1.PodReady {
+			if cond.Status == corev1.ConditionTrue {
+				return nil
+			}
+			return []string{fmt.Sprintf("pod %s: condition Ready is %s: %s", p.Name, cond.Status, cond.Message)}
+		}
+	}
+	return []string{fmt.Sprintf("pod %s: condition Ready not reported yet", p.Name)}
+}
+
+func crashLoopingContainer(p *corev1.Pod) string {
+	for _, cs := range p.Status.ContainerStatuses {
+		if cs.State.Waiting != nil && cs.State.Waiting.Reason == "CrashLoopBackOff" {
+			return cs.Name
+		}
+	}
+	return ""
+}
+
+func pvcNotReady(pvc *corev1.PersistentVolumeClaim) []string {
+	if pvc.Status.Phase != corev1.ClaimBound {
+		return []string{fmt.Sprintf("persistentvolumeclaim %s: phase is %s, not Bound", pvc.Name, pvc.Status.Phase)}
+	}
+	return nil
+}
+
+func serviceNotReady(svc *corev1.Service) []string {
+	if svc.Spec.Type == corev1.ServiceTypeLoadBalancer {
+		if len(svc.Status.LoadBalancer.Ingress) == 0 {
+			return []string{fmt.Sprintf("service %s: load balancer ingress not yet assigned", svc.Name)}
+		}
+		return nil
+	}
+	if svc.Spec.ClusterIP == "" || svc.Spec.ClusterIP == corev1.ClusterIPNone {
+		if svc.Spec.ClusterIP == corev1.ClusterIPNone {
+			// Headless services never get a ClusterIP; they're ready as soon as they exist.
+			return nil
+		}
+		return []string{fmt.Sprintf("service %s: cluster IP not yet assigned", svc.Name)}
+	}
+	return nil
+}
+
+const (
+	// GroupStatefulSetsAnnotation, when present on a StatefulSet, lists
+	// additional comma-separated StatefulSet names (same namespace) that
+	// must also be ready before the annotated object is considered ready.
+	// A LeaderWorkerSet's leader StatefulSet carries this so its per-group
+	// worker StatefulSets -- which the leader's own Pod selector never
+	// matches -- are still checked; without it a workspace could report
+	// Ready while every non-leader rank is still Pending/CrashLoopBackOff.
+	GroupStatefulSetsAnnotation = "kaito.sh/lws-worker-statefulsets"
+	// GroupServicesAnnotation is the Service analogue of
+	// GroupStatefulSetsAnnotation, for per-group headless Services.
+	GroupServicesAnnotation = "kaito.sh/lws-headless-services"
+)
+
+// ownedObjectsNotReady walks the Pods, PVCs (from volumeClaimTemplates), and
+// headless Service owned by a StatefulSet, plus any sibling StatefulSets and
+// Services named in GroupStatefulSetsAnnotation/GroupServicesAnnotation, so
+// that a distributed-inference workspace only reports Ready once every rank
+// pod in the whole group -- leader and workers alike -- is actually serving.
+func ownedObjectsNotReady(ctx context.Context, s *appsv1.StatefulSet, kubeClient client.Client) ([]string, error) {
+	reasons, err := podsNotReadyForStatefulSet(ctx, s, kubeClient)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, vct := range s.Spec.VolumeClaimTemplates {
+		for ordinal := int32(0); ordinal < derefReplicas(s.Spec.Replicas); ordinal++ {
+			pvc := &corev1.PersistentVolumeClaim{}
+			name := fmt.Sprintf("%s-%s-%d", vct.Name, s.Name, ordinal)
+			err := kubeClient.Get(ctx, client.ObjectKey{Name: name, Namespace: s.Namespace}, pvc)
+			if errors.IsNotFound(err) {
+				reasons = append(reasons, fmt.Sprintf("persistentvolumeclaim %s: not yet created", name))
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			reasons = append(reasons, pvcNotReady(pvc)...)
+		}
+	}
+
+	svcNotReady, err := serviceNotReadyByName(ctx, s.Namespace, s.Spec.ServiceName, kubeClient)
+	if err != nil {
+		return nil, err
+	}
+	reasons = append(reasons, svcNotReady...)
+
+	for _, name := range splitAnnotation(s.Annotations[GroupStatefulSetsAnnotation]) {
+		sibling := &appsv1.StatefulSet{}
+		if err := kubeClient.Get(ctx, client.ObjectKey{Name: name, Namespace: s.Namespace}, sibling); err != nil {
+			if errors.IsNotFound(err) {
+				reasons = append(reasons, fmt.Sprintf("statefulset %s: not yet created", name))
+				continue
+			}
+			return nil, err
+		}
+		reasons = append(reasons, statefulSetNotReady(sibling)...)
+		siblingPods, err := podsNotReadyForStatefulSet(ctx, sibling, kubeClient)
+		if err != nil {
+			return nil, err
+		}
+		reasons = append(reasons, siblingPods...)
+	}
+
+	for _, name := range splitAnnotation(s.Annotations[GroupServicesAnnotation]) {
+		svcNotReady, err := serviceNotReadyByName(ctx, s.Namespace, name, kubeClient)
+		if err != nil {
+			return nil, err
+		}
+		reasons = append(reasons, svcNotReady...)
+	}
+
+	return reasons, nil
+}
+
+func podsNotReadyForStatefulSet(ctx context.Context, s *appsv1.StatefulSet, kubeClient client.Client) ([]string, error) {
+	selector, err := labels.ValidatedSelectorFromSet(s.Spec.Selector.MatchLabels)
+	if err != nil {
+		return nil, err
+	}
+
+	podList := &corev1.PodList{}
+	if err := kubeClient.List(ctx, podList, client.InNamespace(s.Namespace), client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return nil, err
+	}
+
+	var reasons []string
+	for i := range podList.Items {
+		reasons = append(reasons, podNotReady(&podList.Items[i])...)
+	}
+	return reasons, nil
+}
+
+func serviceNotReadyByName(ctx context.Context, namespace, name string, kubeClient client.Client) ([]string, error) {
+	if name == "" {
+		return nil, nil
+	}
+	svc := &corev1.Service{}
+	err := kubeClient.Get(ctx, client.ObjectKey{Name: name, Namespace: namespace}, svc)
+	if errors.IsNotFound(err) {
+		return []string{fmt.Sprintf("service %s: not yet created", name)}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return serviceNotReady(svc), nil
+}
+
+func splitAnnotation(v string) []string {
+	if v == "" {
+		return nil
+	}
+	return strings.Split(v, ",")
+}
+
+func derefReplicas(r *int32) int32 {
+	if r == nil {
+		return 1
+	}
+	return *r
+}
@@ -0,0 +1,226 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package statuscheck
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func int32Ptr(i int32) *int32 { return &i }
+
+func TestDeploymentNotReadyScalesPercentMaxUnavailable(t *testing.T) {
+	pct := intstr.FromString("25%")
+	d := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "llama"},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: int32Ptr(8),
+			Strategy: appsv1.DeploymentStrategy{
+				RollingUpdate: &appsv1.RollingUpdateDeployment{MaxUnavailable: &pct},
+			},
+		},
+		Status: appsv1.DeploymentStatus{
+			ObservedGeneration: 1,
+			UpdatedReplicas:    8,
+			// 25% of 8 replicas is 2, so 6 available should already satisfy
+			// minAvailable. The old IntValue()-based code truncated 25% to
+			// 0, which would have demanded all 8 and failed this case.
+			AvailableReplicas: 6,
+		},
+	}
+
+	if reasons := deploymentNotReady(d); len(reasons) != 0 {
+		t.Fatalf("expected no not-ready reasons with 6/8 available and 25%% maxUnavailable, got %v", reasons)
+	}
+}
+
+func TestDeploymentNotReadyStillCatchesTooFewAvailable(t *testing.T) {
+	pct := intstr.FromString("25%")
+	d := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "llama"},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: int32Ptr(8),
+			Strategy: appsv1.DeploymentStrategy{
+				RollingUpdate: &appsv1.RollingUpdateDeployment{MaxUnavailable: &pct},
+			},
+		},
+		Status: appsv1.DeploymentStatus{
+			ObservedGeneration: 1,
+			UpdatedReplicas:    8,
+			AvailableReplicas:  5, // below the 6-replica minimum for 25% of 8
+		},
+	}
+
+	reasons := deploymentNotReady(d)
+	if len(reasons) == 0 {
+		t.Fatal("expected a not-ready reason with only 5/8 available and 25% maxUnavailable")
+	}
+}
+
+func TestDeploymentNotReadyRoundsFractionalMaxUnavailableDown(t *testing.T) {
+	pct := intstr.FromString("10%")
+	d := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "llama"},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: int32Ptr(1),
+			Strategy: appsv1.DeploymentStrategy{
+				RollingUpdate: &appsv1.RollingUpdateDeployment{MaxUnavailable: &pct},
+			},
+		},
+		Status: appsv1.DeploymentStatus{
+			ObservedGeneration: 1,
+			UpdatedReplicas:    1,
+			// 10% of 1 replica rounds down to 0 maxUnavailable (matching the
+			// deployment controller), so minAvailable is 1 and 0 available
+			// replicas must be reported not ready. Rounding up instead would
+			// compute maxUnavailable=1, minAvailable=0, and wrongly call this
+			// ready with zero available replicas.
+			AvailableReplicas: 0,
+		},
+	}
+
+	reasons := deploymentNotReady(d)
+	if len(reasons) == 0 {
+		t.Fatal("expected a not-ready reason for 0/1 available replicas with 10% maxUnavailable rounded down to 0")
+	}
+}
+
+func TestJobNotReady(t *testing.T) {
+	cases := []struct {
+		name      string
+		job       *batchv1.Job
+		wantErr   bool
+		wantReady bool
+	}{
+		{
+			name: "complete",
+			job: &batchv1.Job{Status: batchv1.JobStatus{Conditions: []batchv1.JobCondition{
+				{Type: batchv1.JobComplete, Status: corev1.ConditionTrue},
+			}}},
+			wantReady: true,
+		},
+		{
+			name: "failed",
+			job: &batchv1.Job{Status: batchv1.JobStatus{Conditions: []batchv1.JobCondition{
+				{Type: batchv1.JobFailed, Status: corev1.ConditionTrue, Message: "boom"},
+			}}},
+			wantErr: true,
+		},
+		{
+			name: "still running",
+			job: &batchv1.Job{
+				Spec:   batchv1.JobSpec{Completions: int32Ptr(2)},
+				Status: batchv1.JobStatus{Succeeded: 1},
+			},
+			wantReady: false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			reasons, err := jobNotReady(tc.job)
+			if tc.wantErr && err == nil {
+				t.Fatal("expected an error for a failed job")
+			}
+			if !tc.wantErr && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			gotReady := err == nil && len(reasons) == 0
+			if gotReady != tc.wantReady {
+				t.Fatalf("job ready = %v, want %v (reasons=%v, err=%v)", gotReady, tc.wantReady, reasons, err)
+			}
+		})
+	}
+}
+
+func TestOwnedObjectsNotReadyCatchesUnreadyPodUnboundPVCAndService(t *testing.T) {
+	sts := &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "llama-leader", Namespace: "default"},
+		Spec: appsv1.StatefulSetSpec{
+			Replicas:    int32Ptr(1),
+			ServiceName: "llama-headless",
+			Selector:    &metav1.LabelSelector{MatchLabels: map[string]string{"app": "llama"}},
+			VolumeClaimTemplates: []corev1.PersistentVolumeClaim{
+				{ObjectMeta: metav1.ObjectMeta{Name: "data"}},
+			},
+		},
+	}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "llama-leader-0", Namespace: "default", Labels: map[string]string{"app": "llama"}},
+		Status: corev1.PodStatus{
+			Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionFalse, Message: "not ready yet"}},
+		},
+	}
+	pvc := &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: "data-llama-leader-0", Namespace: "default"},
+		Status:     corev1.PersistentVolumeClaimStatus{Phase: corev1.ClaimPending},
+	}
+	svc := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "llama-headless", Namespace: "default"},
+		Spec:       corev1.ServiceSpec{ClusterIP: ""},
+	}
+
+	kubeClient := fake.NewClientBuilder().WithObjects(sts, pod, pvc, svc).Build()
+
+	reasons, err := ownedObjectsNotReady(context.Background(), sts, kubeClient)
+	if err != nil {
+		t.Fatalf("ownedObjectsNotReady returned error: %v", err)
+	}
+	if len(reasons) == 0 {
+		t.Fatal("expected not-ready reasons for an unready pod, an unbound pvc, and an unassigned service")
+	}
+	joined := strings.Join(reasons, "; ")
+	for _, want := range []string{"pod llama-leader-0", "persistentvolumeclaim data-llama-leader-0", "service llama-headless"} {
+		if !strings.Contains(joined, want) {
+			t.Errorf("expected not-ready reasons to mention %q, got %q", want, joined)
+		}
+	}
+}
+
+func TestOwnedObjectsNotReadyWalksGroupAnnotationsToSiblingStatefulSets(t *testing.T) {
+	leader := &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "llama-leader",
+			Namespace: "default",
+			Annotations: map[string]string{
+				GroupStatefulSetsAnnotation: "llama-worker-0",
+				GroupServicesAnnotation:     "llama-worker-0-headless",
+			},
+		},
+		Spec: appsv1.StatefulSetSpec{
+			Replicas: int32Ptr(1),
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "llama", "role": "leader"}},
+		},
+	}
+	leaderPod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "llama-leader-0", Namespace: "default", Labels: map[string]string{"app": "llama", "role": "leader"}},
+		Status:     corev1.PodStatus{Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}}},
+	}
+
+	kubeClient := fake.NewClientBuilder().WithObjects(leader, leaderPod).Build()
+
+	// Neither the worker StatefulSet nor its headless Service named in the
+	// group annotations exist yet, so the leader -- despite its own pod
+	// being ready -- must still be reported not-ready on their behalf; this
+	// is exactly the gap a leader-only readiness check would miss.
+	reasons, err := ownedObjectsNotReady(context.Background(), leader, kubeClient)
+	if err != nil {
+		t.Fatalf("ownedObjectsNotReady returned error: %v", err)
+	}
+
+	joined := strings.Join(reasons, "; ")
+	if !strings.Contains(joined, "statefulset llama-worker-0: not yet created") {
+		t.Errorf("expected sibling statefulset llama-worker-0 to be reported not yet created, got %q", joined)
+	}
+	if !strings.Contains(joined, "service llama-worker-0-headless: not yet created") {
+		t.Errorf("expected sibling service llama-worker-0-headless to be reported not yet created, got %q", joined)
+	}
+}
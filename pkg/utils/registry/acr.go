@@ -0,0 +1,123 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// acrRefreshTokenUsername is the well-known username ACR assigns to every
+// refresh token it issues, regardless of which principal requested it.
+const acrRefreshTokenUsername = "00000000-0000-0000-0000-000000000000"
+
+// ACRTokenFetcher exchanges an Azure AD service principal for a short-lived
+// ACR refresh token, via the same two-step flow `az acr login` uses: an AAD
+// client-credentials token scoped to ACR, then an ACR /oauth2/exchange call
+// trading that AAD token for a registry refresh token.
+type ACRTokenFetcher struct {
+	SP ACRServicePrincipal
+
+	// httpClient is overridable in tests; defaults to http.DefaultClient.
+	httpClient *http.Client
+}
+
+func (a *ACRTokenFetcher) client() *http.Client {
+	if a.httpClient != nil {
+		return a.httpClient
+	}
+	return http.DefaultClient
+}
+
+// FetchToken implements TokenFetcher.
+func (a *ACRTokenFetcher) FetchToken(ctx context.Context, registryHost string) (username, password string, expiresAt time.Time, err error) {
+	aadToken, expiresIn, err := a.fetchAADToken(ctx)
+	if err != nil {
+		return "", "", time.Time{}, fmt.Errorf("failed to fetch AAD token: %w", err)
+	}
+
+	refreshToken, err := a.exchangeForACRRefreshToken(ctx, registryHost, aadToken)
+	if err != nil {
+		return "", "", time.Time{}, fmt.Errorf("failed to exchange AAD token for ACR refresh token: %w", err)
+	}
+
+	return acrRefreshTokenUsername, refreshToken, time.Now().Add(expiresIn), nil
+}
+
+// fetchAADToken runs the OAuth2 client-credentials grant against Azure AD,
+// scoped to ACR, returning the access token and its lifetime.
+func (a *ACRTokenFetcher) fetchAADToken(ctx context.Context) (string, time.Duration, error) {
+	tokenURL := fmt.Sprintf("https://login.microsoftonline.com/%s/oauth2/v2.0/token", a.SP.TenantID)
+	form := url.Values{
+		"grant_type":    {"client_credentials"},
+		"client_id":     {a.SP.ClientID},
+		"client_secret": {a.SP.ClientSecret},
+		"scope":         {"https://containerregistry.azure.net/.default"},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", 0, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := a.client().Do(req)
+	if err != nil {
+		return "", 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", 0, fmt.Errorf("AAD token endpoint returned status %d", resp.StatusCode)
+	}
+
+	var body struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", 0, err
+	}
+	return body.AccessToken, time.Duration(body.ExpiresIn) * time.Second, nil
+}
+
+// exchangeForACRRefreshToken trades aadToken for an ACR refresh token scoped
+// to registryHost, per ACR's documented token exchange protocol.
+func (a *ACRTokenFetcher) exchangeForACRRefreshToken(ctx context.Context, registryHost, aadToken string) (string, error) {
+	exchangeURL := fmt.Sprintf("https://%s/oauth2/exchange", registryHost)
+	form := url.Values{
+		"grant_type":   {"access_token"},
+		"service":      {registryHost},
+		"tenant":       {a.SP.TenantID},
+		"access_token": {aadToken},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, exchangeURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := a.client().Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("ACR token exchange endpoint returned status %d", resp.StatusCode)
+	}
+
+	var body struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", err
+	}
+	return body.RefreshToken, nil
+}
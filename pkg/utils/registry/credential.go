@@ -0,0 +1,216 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+
+// Package registry synthesizes and maintains the dockerconfigjson image pull
+// secrets a workspace needs to reach private preset and adapter registries,
+// so users declare credentials once (on the Workspace spec or a cluster-scoped
+// KaitoRegistry) instead of hand-creating a Secret per namespace.
+package registry
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Kind identifies what kind of credential material a Credential carries.
+type Kind string
+
+const (
+	KindUsernamePassword    Kind = "UsernamePassword"
+	KindDockerConfigJSON    Kind = "DockerConfigJSON"
+	KindACRServicePrincipal Kind = "ACRServicePrincipal"
+	KindECRAccessKey        Kind = "ECRAccessKey"
+	KindGCRJSONKey          Kind = "GCRJSONKey"
+)
+
+// Credential is the fully resolved credential material for a single
+// registry host: every Secret a CredentialRef pointed at has already been
+// read and its value copied in here. It never appears on a CRD spec --
+// ResolveCredentials is what produces it from a CredentialRef -- so it's
+// safe for it to carry plaintext secret material in memory.
+type Credential struct {
+	// Registry is the host the credential applies to, e.g.
+	// "myregistry.azurecr.io" or "123456789012.dkr.ecr.us-east-1.amazonaws.com".
+	Registry string
+	Kind     Kind
+
+	Username string
+	Password string
+
+	// DockerConfigJSON is used as-is when Kind is KindDockerConfigJSON.
+	DockerConfigJSON []byte
+
+	// ACR is populated when Kind is KindACRServicePrincipal: the service
+	// principal is exchanged for a short-lived ACR refresh token.
+	ACR *ACRServicePrincipal
+
+	// ECR is populated when Kind is KindECRAccessKey: the access key is
+	// exchanged for a short-lived ECR authorization token.
+	ECR *ECRAccessKey
+
+	// GCR is populated when Kind is KindGCRJSONKey: the service account
+	// JSON key is used directly as the basic-auth password, with
+	// "_json_key" as the username, per GCR's documented convention.
+	GCR *GCRJSONKey
+}
+
+// ACRServicePrincipal identifies the Azure AD application used to mint
+// short-lived ACR refresh tokens for Registry.
+type ACRServicePrincipal struct {
+	TenantID     string
+	ClientID     string
+	ClientSecret string
+}
+
+// ECRAccessKey identifies the IAM principal used to mint short-lived ECR
+// authorization tokens for Registry.
+type ECRAccessKey struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	Region          string
+}
+
+// GCRJSONKey is a GCR/Artifact Registry service account key.
+type GCRJSONKey struct {
+	JSONKey []byte
+}
+
+// SecretKeyRef names a key within a Secret in the Workspace's own namespace
+// holding one piece of credential material. CredentialRef holds these
+// instead of the material itself, so a Workspace spec -- readable under far
+// broader RBAC than Secret-read, and commonly captured in etcd snapshots,
+// audit logs, and GitOps diffs -- never carries plaintext cloud credentials.
+type SecretKeyRef struct {
+	// Name is the referenced Secret's name.
+	Name string `json:"name"`
+
+	// Key is the key within the Secret's Data holding the credential
+	// material. Defaults to "value" if empty.
+	Key string `json:"key,omitempty"`
+}
+
+// CredentialRef is what a Workspace spec actually carries for a registry
+// credential: everything needed to resolve the real material via
+// ResolveCredentials, but never the material itself. Exactly one of the
+// kind-specific fields is populated, matching Kind.
+type CredentialRef struct {
+	// Registry is the host the credential applies to, e.g.
+	// "myregistry.azurecr.io" or "123456789012.dkr.ecr.us-east-1.amazonaws.com".
+	Registry string `json:"registry"`
+	Kind     Kind   `json:"kind"`
+
+	// Username is the basic-auth username for KindUsernamePassword. It
+	// isn't secret material on its own, so it's stored inline like any
+	// other spec field.
+	Username string `json:"username,omitempty"`
+
+	// PasswordSecretRef resolves to the basic-auth password for
+	// KindUsernamePassword.
+	PasswordSecretRef *SecretKeyRef `json:"passwordSecretRef,omitempty"`
+
+	// DockerConfigJSONSecretRef resolves to a ready-made .dockerconfigjson
+	// payload for KindDockerConfigJSON.
+	DockerConfigJSONSecretRef *SecretKeyRef `json:"dockerConfigJSONSecretRef,omitempty"`
+
+	// ACR is populated when Kind is KindACRServicePrincipal.
+	ACR *ACRServicePrincipalRef `json:"acr,omitempty"`
+
+	// ECR is populated when Kind is KindECRAccessKey.
+	ECR *ECRAccessKeyRef `json:"ecr,omitempty"`
+
+	// GCR is populated when Kind is KindGCRJSONKey.
+	GCR *GCRJSONKeyRef `json:"gcr,omitempty"`
+}
+
+// ACRServicePrincipalRef identifies the Azure AD application used to mint
+// short-lived ACR refresh tokens for CredentialRef.Registry. TenantID and
+// ClientID aren't secret; ClientSecretRef is.
+type ACRServicePrincipalRef struct {
+	TenantID        string       `json:"tenantID"`
+	ClientID        string       `json:"clientID"`
+	ClientSecretRef SecretKeyRef `json:"clientSecretRef"`
+}
+
+// ECRAccessKeyRef identifies the IAM principal used to mint short-lived ECR
+// authorization tokens for CredentialRef.Registry. AccessKeyID and Region
+// aren't secret; SecretAccessKeyRef is.
+type ECRAccessKeyRef struct {
+	AccessKeyID        string       `json:"accessKeyID"`
+	SecretAccessKeyRef SecretKeyRef `json:"secretAccessKeyRef"`
+	Region             string       `json:"region"`
+}
+
+// GCRJSONKeyRef references a GCR/Artifact Registry service account key.
+type GCRJSONKeyRef struct {
+	JSONKeyRef SecretKeyRef `json:"jsonKeyRef"`
+}
+
+// dockerConfigJSON builds the .dockerconfigjson payload stored in the
+// resulting Secret's Data, basic-auth encoding username/password for the
+// credential's registry host.
+func dockerConfigJSON(registryHost, username, password string) ([]byte, error) {
+	auth := base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%s:%s", username, password)))
+	cfg := map[string]interface{}{
+		"auths": map[string]interface{}{
+			registryHost: map[string]string{
+				"username": username,
+				"password": password,
+				"auth":     auth,
+			},
+		},
+	}
+	return json.Marshal(cfg)
+}
+
+// SecretName returns the stable, DNS-1123-safe name of the image pull
+// secret synthesized for registryHost, so repeated calls for the same host
+// resolve to the same Secret instead of creating duplicates.
+func SecretName(registryHost string) string {
+	sum := sha256.Sum256([]byte(registryHost))
+	return fmt.Sprintf("kaito-regcred-%x", sum[:8])
+}
+
+// buildSecret renders the Kubernetes Secret for cred, resolving its
+// credential material into a .dockerconfigjson payload.
+func buildSecret(namespace string, cred Credential) (*corev1.Secret, error) {
+	var data []byte
+	var err error
+
+	switch cred.Kind {
+	case KindDockerConfigJSON:
+		data = cred.DockerConfigJSON
+	case KindUsernamePassword:
+		data, err = dockerConfigJSON(cred.Registry, cred.Username, cred.Password)
+	case KindACRServicePrincipal, KindECRAccessKey:
+		// Username/Password must already hold the short-lived token a
+		// Refresher minted for this credential (see acr.go/ecr.go); without
+		// it we'd silently synthesize a Secret with an empty "auth for :"
+		// that can never authenticate.
+		if cred.Username == "" || cred.Password == "" {
+			return nil, fmt.Errorf("registry credential for %s (kind %s) has no token yet; EnsureRefresher must fetch one before the secret is built", cred.Registry, cred.Kind)
+		}
+		data, err = dockerConfigJSON(cred.Registry, cred.Username, cred.Password)
+	case KindGCRJSONKey:
+		if cred.GCR == nil {
+			return nil, fmt.Errorf("registry credential for %s (kind %s) has no GCR JSON key set", cred.Registry, cred.Kind)
+		}
+		data, err = dockerConfigJSON(cred.Registry, "_json_key", string(cred.GCR.JSONKey))
+	default:
+		return nil, fmt.Errorf("unsupported registry credential kind %q", cred.Kind)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to build dockerconfigjson for registry %s: %w", cred.Registry, err)
+	}
+
+	return &corev1.Secret{
+		ObjectMeta: metaObjectMeta(SecretName(cred.Registry), namespace, cred.Registry),
+		Type:       corev1.SecretTypeDockerConfigJson,
+		Data: map[string][]byte{
+			corev1.DockerConfigJsonKey: data,
+		},
+	}, nil
+}
@@ -0,0 +1,233 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package registry
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// registryHostLabel records which registry host a synthesized Secret serves.
+// EnsureImagePullSecrets finds an existing Secret by SecretName, not by this
+// label.
+const registryHostLabel = "kaito.sh/registry-host"
+
+func metaObjectMeta(name, namespace, registryHost string) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Name:      name,
+		Namespace: namespace,
+		Labels:    map[string]string{registryHostLabel: sanitizeLabelValue(registryHost)},
+	}
+}
+
+// sanitizeLabelValue truncates a registry host to fit the 63-character
+// Kubernetes label value limit; SecretName's hash suffix is what actually
+// guarantees uniqueness, this label is only for human inspection.
+func sanitizeLabelValue(v string) string {
+	const maxLabelLen = 63
+	if len(v) > maxLabelLen {
+		return v[:maxLabelLen]
+	}
+	return v
+}
+
+// EnsureImagePullSecrets synthesizes a namespaced dockerconfigjson Secret
+// for every distinct registry host in creds and returns references to them,
+// de-duplicated so a preset and an adapter pointing at the same registry
+// share one Secret.
+func EnsureImagePullSecrets(ctx context.Context, kubeClient client.Client, namespace string, creds []Credential) ([]corev1.LocalObjectReference, error) {
+	seen := make(map[string]bool, len(creds))
+	var refs []corev1.LocalObjectReference
+
+	for _, cred := range creds {
+		if seen[cred.Registry] {
+			continue
+		}
+		seen[cred.Registry] = true
+
+		// ACR/ECR credentials carry a service principal / access key, not a
+		// token, the first time they reach here: exchange it for a token
+		// (and start a background Refresher to keep renewing it) before
+		// building the Secret. FetchAndApply re-enters this function with
+		// Username/Password already populated, so this only recurses once.
+		if cred.Username == "" && cred.Password == "" {
+			fetcher, err := tokenFetcherFor(cred)
+			if err != nil {
+				return nil, err
+			}
+			if fetcher != nil {
+				if err := EnsureRefresher(ctx, kubeClient, namespace, cred, fetcher); err != nil {
+					return nil, fmt.Errorf("failed to provision token-based credential for registry %s: %w", cred.Registry, err)
+				}
+				refs = append(refs, corev1.LocalObjectReference{Name: SecretName(cred.Registry)})
+				continue
+			}
+		}
+
+		secret, err := buildSecret(namespace, cred)
+		if err != nil {
+			return nil, err
+		}
+		if err := createOrUpdateSecret(ctx, kubeClient, secret); err != nil {
+			return nil, err
+		}
+		klog.InfoS("ensured image pull secret", "registry", cred.Registry, "secret", secret.Name, "namespace", namespace)
+		refs = append(refs, corev1.LocalObjectReference{Name: secret.Name})
+	}
+
+	return refs, nil
+}
+
+// tokenFetcherFor returns the TokenFetcher that mints tokens for cred's kind,
+// or nil for kinds that carry their own credential material directly (e.g.
+// KindUsernamePassword) and need no exchange.
+func tokenFetcherFor(cred Credential) (TokenFetcher, error) {
+	switch cred.Kind {
+	case KindACRServicePrincipal:
+		if cred.ACR == nil {
+			return nil, fmt.Errorf("registry credential for %s is ACRServicePrincipal but has no ACR service principal set", cred.Registry)
+		}
+		return &ACRTokenFetcher{SP: *cred.ACR}, nil
+	case KindECRAccessKey:
+		if cred.ECR == nil {
+			return nil, fmt.Errorf("registry credential for %s is ECRAccessKey but has no ECR access key set", cred.Registry)
+		}
+		return &ECRTokenFetcher{Key: *cred.ECR}, nil
+	default:
+		return nil, nil
+	}
+}
+
+// createOrUpdateSecret creates secret, or, if it already exists, refreshes
+// its dockerconfigjson payload in place so a rotated credential takes effect
+// without the caller having to delete the Secret first.
+func createOrUpdateSecret(ctx context.Context, kubeClient client.Client, secret *corev1.Secret) error {
+	err := kubeClient.Create(ctx, secret)
+	if err == nil || !errors.IsAlreadyExists(err) {
+		return err
+	}
+
+	return retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+		existing := &corev1.Secret{}
+		if err := kubeClient.Get(ctx, client.ObjectKeyFromObject(secret), existing); err != nil {
+			return err
+		}
+		existing.Data = secret.Data
+		existing.Labels = secret.Labels
+		return kubeClient.Update(ctx, existing)
+	})
+}
+
+// TokenFetcher mints a short-lived username/password pair for registry,
+// along with when it expires. ACR and ECR implementations exchange the
+// service principal / access key configured on the Credential for a
+// registry-scoped token; see Refresher.
+type TokenFetcher interface {
+	FetchToken(ctx context.Context, registry string) (username, password string, expiresAt time.Time, err error)
+}
+
+// Refresher keeps one credential's Secret in sync with a TokenFetcher that
+// mints short-lived registry tokens (ACR refresh tokens, ECR authorization
+// tokens), re-fetching and re-applying on a timer well before expiry.
+type Refresher struct {
+	KubeClient client.Client
+	Namespace  string
+	Credential Credential
+	Fetcher    TokenFetcher
+
+	// RefreshBefore is how long before the minted token expires to fetch
+	// its replacement. Defaults to 10 minutes if zero.
+	RefreshBefore time.Duration
+}
+
+// FetchAndApply fetches one token from r.Fetcher and applies it to r's
+// Secret, returning when the minted token expires so the caller can schedule
+// the next refresh. It is split out of Start so the first fetch can be done
+// synchronously by EnsureRefresher, surfacing a bad credential immediately
+// instead of only logging it from a background goroutine.
+func (r *Refresher) FetchAndApply(ctx context.Context) (time.Time, error) {
+	username, password, expiresAt, err := r.Fetcher.FetchToken(ctx, r.Credential.Registry)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to fetch registry token for %s: %w", r.Credential.Registry, err)
+	}
+
+	r.Credential.Username = username
+	r.Credential.Password = password
+	if _, err := EnsureImagePullSecrets(ctx, r.KubeClient, r.Namespace, []Credential{r.Credential}); err != nil {
+		return time.Time{}, fmt.Errorf("failed to apply refreshed image pull secret for %s: %w", r.Credential.Registry, err)
+	}
+	return expiresAt, nil
+}
+
+// Start blocks, refreshing the credential's Secret until ctx is cancelled.
+// Callers should run it in its own goroutine; EnsureRefresher is the usual
+// entry point rather than calling Start directly.
+func (r *Refresher) Start(ctx context.Context) {
+	refreshBefore := r.RefreshBefore
+	if refreshBefore <= 0 {
+		refreshBefore = 10 * time.Minute
+	}
+
+	for {
+		expiresAt, err := r.FetchAndApply(ctx)
+		if err != nil {
+			klog.ErrorS(err, "failed to refresh registry token, retrying", "registry", r.Credential.Registry)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(refreshBefore):
+				continue
+			}
+		}
+
+		wait := time.Until(expiresAt) - refreshBefore
+		if wait <= 0 {
+			wait = refreshBefore
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+	}
+}
+
+// refreshersStarted dedupes EnsureRefresher across reconciles: without it,
+// every reconcile of a workspace referencing the same ACR/ECR registry would
+// spawn another Start goroutine racing to refresh the same Secret.
+var refreshersStarted sync.Map
+
+// EnsureRefresher performs one synchronous token fetch for cred so a bad
+// service principal / access key is reported to the caller immediately,
+// then, the first time it is called for namespace+cred.Registry, launches a
+// background Refresher to keep the Secret's token renewed thereafter.
+func EnsureRefresher(ctx context.Context, kubeClient client.Client, namespace string, cred Credential, fetcher TokenFetcher) error {
+	r := &Refresher{
+		KubeClient: kubeClient,
+		Namespace:  namespace,
+		Credential: cred,
+		Fetcher:    fetcher,
+	}
+
+	if _, err := r.FetchAndApply(ctx); err != nil {
+		return err
+	}
+
+	key := namespace + "/" + cred.Registry
+	if _, alreadyStarted := refreshersStarted.LoadOrStore(key, struct{}{}); alreadyStarted {
+		return nil
+	}
+	// The Refresher must keep renewing for the process lifetime, not just
+	// for the duration of the reconcile that first triggered it.
+	go r.Start(context.Background())
+	return nil
+}
@@ -0,0 +1,125 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestEnsureImagePullSecretsDedupesByRegistry(t *testing.T) {
+	kubeClient := fake.NewClientBuilder().Build()
+	creds := []Credential{
+		{Registry: "myregistry.azurecr.io", Kind: KindUsernamePassword, Username: "u1", Password: "p1"},
+		{Registry: "myregistry.azurecr.io", Kind: KindUsernamePassword, Username: "u2", Password: "p2"},
+		{Registry: "other.azurecr.io", Kind: KindUsernamePassword, Username: "u3", Password: "p3"},
+	}
+
+	refs, err := EnsureImagePullSecrets(context.Background(), kubeClient, "default", creds)
+	if err != nil {
+		t.Fatalf("EnsureImagePullSecrets returned error: %v", err)
+	}
+	if len(refs) != 2 {
+		t.Fatalf("expected 2 deduped secret refs, got %d: %v", len(refs), refs)
+	}
+}
+
+func TestBuildSecretRoundTrip(t *testing.T) {
+	secret, err := buildSecret("default", Credential{
+		Registry: "myregistry.azurecr.io",
+		Kind:     KindUsernamePassword,
+		Username: "user",
+		Password: "pass",
+	})
+	if err != nil {
+		t.Fatalf("buildSecret returned error: %v", err)
+	}
+	if secret.Type != corev1.SecretTypeDockerConfigJson {
+		t.Fatalf("expected dockerconfigjson secret type, got %s", secret.Type)
+	}
+
+	var cfg struct {
+		Auths map[string]struct {
+			Username string `json:"username"`
+			Password string `json:"password"`
+		} `json:"auths"`
+	}
+	if err := json.Unmarshal(secret.Data[corev1.DockerConfigJsonKey], &cfg); err != nil {
+		t.Fatalf("failed to unmarshal dockerconfigjson: %v", err)
+	}
+	auth, ok := cfg.Auths["myregistry.azurecr.io"]
+	if !ok {
+		t.Fatalf("expected auths entry for myregistry.azurecr.io, got %v", cfg.Auths)
+	}
+	if auth.Username != "user" || auth.Password != "pass" {
+		t.Fatalf("unexpected auth entry: %+v", auth)
+	}
+}
+
+func TestResolveCredentialsReadsSecretKeyRef(t *testing.T) {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "acr-client-secret", Namespace: "default"},
+		Data:       map[string][]byte{"value": []byte("super-secret")},
+	}
+	kubeClient := fake.NewClientBuilder().WithObjects(secret).Build()
+
+	refs := []CredentialRef{{
+		Registry: "myregistry.azurecr.io",
+		Kind:     KindACRServicePrincipal,
+		ACR: &ACRServicePrincipalRef{
+			TenantID:        "tenant",
+			ClientID:        "client",
+			ClientSecretRef: SecretKeyRef{Name: "acr-client-secret"},
+		},
+	}}
+
+	creds, err := ResolveCredentials(context.Background(), kubeClient, "default", refs)
+	if err != nil {
+		t.Fatalf("ResolveCredentials returned error: %v", err)
+	}
+	if len(creds) != 1 || creds[0].ACR == nil {
+		t.Fatalf("expected one resolved ACR credential, got %+v", creds)
+	}
+	if creds[0].ACR.ClientSecret != "super-secret" {
+		t.Fatalf("ACR.ClientSecret = %q, want the value read from the referenced secret", creds[0].ACR.ClientSecret)
+	}
+}
+
+func TestResolveCredentialsErrorsOnMissingSecret(t *testing.T) {
+	kubeClient := fake.NewClientBuilder().Build()
+
+	refs := []CredentialRef{{
+		Registry:          "myregistry.azurecr.io",
+		Kind:              KindUsernamePassword,
+		PasswordSecretRef: &SecretKeyRef{Name: "does-not-exist"},
+	}}
+
+	if _, err := ResolveCredentials(context.Background(), kubeClient, "default", refs); err == nil {
+		t.Fatal("expected ResolveCredentials to error when the referenced secret does not exist")
+	}
+}
+
+func TestBuildSecretRejectsEmptyACRCredential(t *testing.T) {
+	_, err := buildSecret("default", Credential{
+		Registry: "myregistry.azurecr.io",
+		Kind:     KindACRServicePrincipal,
+	})
+	if err == nil {
+		t.Fatal("expected buildSecret to reject an ACR credential with no token yet, got nil error")
+	}
+}
+
+func TestBuildSecretRejectsNilGCRCredential(t *testing.T) {
+	_, err := buildSecret("default", Credential{
+		Registry: "us.gcr.io/my-project",
+		Kind:     KindGCRJSONKey,
+	})
+	if err == nil {
+		t.Fatal("expected buildSecret to reject a GCRJSONKey credential with no GCR key set, got nil error")
+	}
+}
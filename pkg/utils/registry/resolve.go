@@ -0,0 +1,106 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package registry
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// defaultSecretKey is the Secret data key SecretKeyRef.Key resolves to when
+// left unset.
+const defaultSecretKey = "value"
+
+// ResolveCredentials reads the Secret named by each ref and returns the
+// resolved Credentials EnsureImagePullSecrets operates on. Call this once
+// per reconcile on the CredentialRefs read straight off the Workspace spec;
+// everything downstream (EnsureImagePullSecrets, buildSecret, the
+// TokenFetchers) deals only in resolved Credentials, never a secret
+// reference, so plaintext material is never written back to the spec.
+func ResolveCredentials(ctx context.Context, kubeClient client.Client, namespace string, refs []CredentialRef) ([]Credential, error) {
+	creds := make([]Credential, 0, len(refs))
+	for _, ref := range refs {
+		cred, err := resolveCredential(ctx, kubeClient, namespace, ref)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve registry credential for %s: %w", ref.Registry, err)
+		}
+		creds = append(creds, cred)
+	}
+	return creds, nil
+}
+
+func resolveCredential(ctx context.Context, kubeClient client.Client, namespace string, ref CredentialRef) (Credential, error) {
+	cred := Credential{Registry: ref.Registry, Kind: ref.Kind, Username: ref.Username}
+
+	switch ref.Kind {
+	case KindUsernamePassword:
+		if ref.PasswordSecretRef == nil {
+			return Credential{}, fmt.Errorf("registry credential for %s is UsernamePassword but has no passwordSecretRef set", ref.Registry)
+		}
+		password, err := resolveSecretKeyRef(ctx, kubeClient, namespace, *ref.PasswordSecretRef)
+		if err != nil {
+			return Credential{}, err
+		}
+		cred.Password = password
+	case KindDockerConfigJSON:
+		if ref.DockerConfigJSONSecretRef == nil {
+			return Credential{}, fmt.Errorf("registry credential for %s is DockerConfigJSON but has no dockerConfigJSONSecretRef set", ref.Registry)
+		}
+		dcj, err := resolveSecretKeyRef(ctx, kubeClient, namespace, *ref.DockerConfigJSONSecretRef)
+		if err != nil {
+			return Credential{}, err
+		}
+		cred.DockerConfigJSON = []byte(dcj)
+	case KindACRServicePrincipal:
+		if ref.ACR == nil {
+			return Credential{}, fmt.Errorf("registry credential for %s is ACRServicePrincipal but has no acr set", ref.Registry)
+		}
+		clientSecret, err := resolveSecretKeyRef(ctx, kubeClient, namespace, ref.ACR.ClientSecretRef)
+		if err != nil {
+			return Credential{}, err
+		}
+		cred.ACR = &ACRServicePrincipal{TenantID: ref.ACR.TenantID, ClientID: ref.ACR.ClientID, ClientSecret: clientSecret}
+	case KindECRAccessKey:
+		if ref.ECR == nil {
+			return Credential{}, fmt.Errorf("registry credential for %s is ECRAccessKey but has no ecr set", ref.Registry)
+		}
+		secretAccessKey, err := resolveSecretKeyRef(ctx, kubeClient, namespace, ref.ECR.SecretAccessKeyRef)
+		if err != nil {
+			return Credential{}, err
+		}
+		cred.ECR = &ECRAccessKey{AccessKeyID: ref.ECR.AccessKeyID, SecretAccessKey: secretAccessKey, Region: ref.ECR.Region}
+	case KindGCRJSONKey:
+		if ref.GCR == nil {
+			return Credential{}, fmt.Errorf("registry credential for %s is GCRJSONKey but has no gcr set", ref.Registry)
+		}
+		jsonKey, err := resolveSecretKeyRef(ctx, kubeClient, namespace, ref.GCR.JSONKeyRef)
+		if err != nil {
+			return Credential{}, err
+		}
+		cred.GCR = &GCRJSONKey{JSONKey: []byte(jsonKey)}
+	default:
+		return Credential{}, fmt.Errorf("unsupported registry credential kind %q", ref.Kind)
+	}
+
+	return cred, nil
+}
+
+func resolveSecretKeyRef(ctx context.Context, kubeClient client.Client, namespace string, ref SecretKeyRef) (string, error) {
+	key := ref.Key
+	if key == "" {
+		key = defaultSecretKey
+	}
+
+	secret := &corev1.Secret{}
+	if err := kubeClient.Get(ctx, client.ObjectKey{Name: ref.Name, Namespace: namespace}, secret); err != nil {
+		return "", fmt.Errorf("failed to get secret %s/%s: %w", namespace, ref.Name, err)
+	}
+	value, ok := secret.Data[key]
+	if !ok {
+		return "", fmt.Errorf("secret %s/%s has no key %q", namespace, ref.Name, key)
+	}
+	return string(value), nil
+}
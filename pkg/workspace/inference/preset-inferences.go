@@ -12,50 +12,17 @@ import (
 	"github.com/kaito-project/kaito/pkg/utils/consts"
 
 	kaitov1alpha1 "github.com/kaito-project/kaito/api/v1alpha1"
+	"github.com/kaito-project/kaito/pkg/inference"
 	"github.com/kaito-project/kaito/pkg/model"
+	"github.com/kaito-project/kaito/pkg/utils/registry"
 	"github.com/kaito-project/kaito/pkg/utils/resources"
 	"github.com/kaito-project/kaito/pkg/workspace/manifests"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
-	"k8s.io/apimachinery/pkg/util/intstr"
-	"k8s.io/klog/v2"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
-const (
-	ProbePath     = "/health"
-	Port5000      = 5000
-	InferenceFile = "inference_api.py"
-)
-
 var (
-	containerPorts = []corev1.ContainerPort{{
-		ContainerPort: int32(Port5000),
-	},
-	}
-
-	livenessProbe = &corev1.Probe{
-		ProbeHandler: corev1.ProbeHandler{
-			HTTPGet: &corev1.HTTPGetAction{
-				Port: intstr.FromInt(Port5000),
-				Path: ProbePath,
-			},
-		},
-		InitialDelaySeconds: 600, // 10 minutes
-		PeriodSeconds:       10,
-	}
-
-	readinessProbe = &corev1.Probe{
-		ProbeHandler: corev1.ProbeHandler{
-			HTTPGet: &corev1.HTTPGetAction{
-				Port: intstr.FromInt(Port5000),
-				Path: ProbePath,
-			},
-		},
-		InitialDelaySeconds: 30,
-		PeriodSeconds:       10,
-	}
-
 	tolerations = []corev1.Toleration{
 		{
 			Effect:   corev1.TaintEffectNoSchedule,
@@ -71,19 +38,61 @@ var (
 	}
 )
 
-func updateTorchParamsForDistributedInference(ctx context.Context, kubeClient client.Client, wObj *kaitov1alpha1.Workspace, inferenceObj *model.PresetParam) error {
-	existingService := &corev1.Service{}
-	err := resources.GetResource(ctx, wObj.Name, wObj.Namespace, kubeClient, existingService)
-	if err != nil {
-		return err
+// workersPerLeader returns how many worker Pods accompany each leader in
+// wObj's replica groups. Workspaces that only set Resource.Count keep the
+// old single-group-per-replica behavior (groupSize 1) for compatibility.
+func workersPerLeader(wObj *kaitov1alpha1.Workspace) int {
+	if wObj.Resource.WorkersPerLeader != nil {
+		return int(*wObj.Resource.WorkersPerLeader)
 	}
+	return 0
+}
 
-	nodes := *wObj.Resource.Count
-	inferenceObj.TorchRunParams["nnodes"] = strconv.Itoa(nodes)
-	inferenceObj.TorchRunParams["nproc_per_node"] = strconv.Itoa(inferenceObj.WorldSize / nodes)
-	if nodes > 1 {
-		inferenceObj.TorchRunParams["node_rank"] = "$(echo $HOSTNAME | grep -o '[^-]*$')"
-		inferenceObj.TorchRunParams["master_addr"] = existingService.Spec.ClusterIP
+// leaderCount returns how many leader/worker replica groups wObj requests,
+// falling back to Resource.Count so workspaces that predate LeaderCount
+// behave as one group per replica, as before.
+func leaderCount(wObj *kaitov1alpha1.Workspace) int {
+	if wObj.Resource.LeaderCount != nil {
+		return int(*wObj.Resource.LeaderCount)
+	}
+	if wObj.Resource.Count != nil {
+		return int(*wObj.Resource.Count)
+	}
+	return 1
+}
+
+// updateTorchParamsForDistributedInference points torchrun at the
+// leader/worker group's own downward-API env vars (LWS_LEADER_ADDRESS,
+// LWS_WORKER_INDEX, LWS_GROUP_SIZE) instead of shell-parsing $HOSTNAME, so
+// node_rank, nnodes, master_addr, and nproc_per_node stay correct across
+// pod rescheduling.
+func updateTorchParamsForDistributedInference(wObj *kaitov1alpha1.Workspace, inferenceObj *model.PresetParam, skuNumGPUs string) error {
+	groupSize := workersPerLeader(wObj) + 1
+
+	// nproc_per_node must match how many GPUs each pod actually has (the
+	// SKU's GPU count), not WorldSize/groupSize: groupSize is a user-chosen
+	// topology knob, so that division could silently under/over-subscribe
+	// the GPUs each pod requests (see prepareInferenceParameters's
+	// resourceReq) or even collapse to 0 processes when WorldSize < groupSize.
+	nprocPerNode := gpusPerNode(skuNumGPUs, inferenceObj.WorldSize)
+	if groupSize > 1 && inferenceObj.WorldSize > 0 && groupSize*nprocPerNode != inferenceObj.WorldSize {
+		// The group's total launched ranks (groupSize*nprocPerNode) must
+		// equal WorldSize, or distributed rendezvous either launches ranks
+		// with no corresponding shard or never reaches WorldSize at all.
+		return fmt.Errorf("resource.workersPerLeader %d (group size %d) combined with %d GPUs per node does not add up to world size %d; set workersPerLeader so (workersPerLeader+1)*<SKU GPU count> equals %d",
+			groupSize-1, groupSize, nprocPerNode, inferenceObj.WorldSize, inferenceObj.WorldSize)
+	}
+
+	inferenceObj.TorchRunParams["nnodes"] = fmt.Sprintf("$%s", manifests.EnvLWSGroupSize)
+	inferenceObj.TorchRunParams["nproc_per_node"] = strconv.Itoa(nprocPerNode)
+	if groupSize > 1 {
+		// LWS_WORKER_INDEX is unset on the leader (rank 0) and is the
+		// worker's 0-based ordinal within its group on a worker Pod, so a
+		// bare "${LWS_WORKER_INDEX:-0}" would make the leader and worker
+		// ordinal 0 collide on rank 0. Shifting worker ordinals up by one
+		// keeps every rank in the group unique.
+		inferenceObj.TorchRunParams["node_rank"] = fmt.Sprintf("$(( ${%s:--1} + 1 ))", manifests.EnvLWSWorkerIndex)
+		inferenceObj.TorchRunParams["master_addr"] = fmt.Sprintf("${%s:-127.0.0.1}", manifests.EnvLWSLeaderAddress)
 		inferenceObj.TorchRunParams["master_port"] = "29500"
 	}
 	if inferenceObj.TorchRunRdzvParams != nil {
@@ -91,42 +100,72 @@ func updateTorchParamsForDistributedInference(ctx context.Context, kubeClient cl
 		inferenceObj.TorchRunRdzvParams["rdzv_id"] = "job"
 		inferenceObj.TorchRunRdzvParams["rdzv_backend"] = "c10d"
 		inferenceObj.TorchRunRdzvParams["rdzv_endpoint"] =
-			fmt.Sprintf("%s-0.%s-headless.%s.svc.cluster.local:29500", wObj.Name, wObj.Name, wObj.Namespace)
+			fmt.Sprintf("${%s:-127.0.0.1}:29500", manifests.EnvLWSLeaderAddress)
 	}
 	return nil
 }
 
-func GetInferenceImageInfo(ctx context.Context, workspaceObj *kaitov1alpha1.Workspace, presetObj *model.PresetParam) (string, []corev1.LocalObjectReference) {
-	imagePullSecretRefs := []corev1.LocalObjectReference{}
-	// Check if the workspace preset's access mode is private
-	if len(workspaceObj.Inference.Adapters) > 0 {
-		for _, adapter := range workspaceObj.Inference.Adapters {
-			for _, secretName := range adapter.Source.ImagePullSecrets {
-				imagePullSecretRefs = append(imagePullSecretRefs, corev1.LocalObjectReference{Name: secretName})
-			}
+// GetInferenceImageInfo resolves the preset's image and the image pull
+// secrets needed to fetch it and any private adapters. Secrets named
+// directly on the Workspace are combined, de-duplicated, with ones
+// auto-provisioned from RegistryCredentials, so users can point at a
+// private adapter or gated preset mirror by declaring credentials once
+// instead of hand-maintaining a Secret per namespace.
+func GetInferenceImageInfo(ctx context.Context, kubeClient client.Client, workspaceObj *kaitov1alpha1.Workspace, presetObj *model.PresetParam) (string, []corev1.LocalObjectReference, error) {
+	secretNames := map[string]bool{}
+	var imagePullSecretRefs []corev1.LocalObjectReference
+	addSecret := func(name string) {
+		if name == "" || secretNames[name] {
+			return
+		}
+		secretNames[name] = true
+		imagePullSecretRefs = append(imagePullSecretRefs, corev1.LocalObjectReference{Name: name})
+	}
+
+	for _, adapter := range workspaceObj.Inference.Adapters {
+		for _, secretName := range adapter.Source.ImagePullSecrets {
+			addSecret(secretName)
 		}
 	}
+
+	var imageName string
 	if string(workspaceObj.Inference.Preset.AccessMode) == string(kaitov1alpha1.ModelImageAccessModePrivate) {
-		imageName := workspaceObj.Inference.Preset.PresetOptions.Image
+		imageName = workspaceObj.Inference.Preset.PresetOptions.Image
 		for _, secretName := range workspaceObj.Inference.Preset.PresetOptions.ImagePullSecrets {
-			imagePullSecretRefs = append(imagePullSecretRefs, corev1.LocalObjectReference{Name: secretName})
+			addSecret(secretName)
 		}
-		return imageName, imagePullSecretRefs
 	} else {
-		imageName := string(workspaceObj.Inference.Preset.Name)
-		imageTag := presetObj.Tag
 		registryName := os.Getenv("PRESET_REGISTRY_NAME")
-		imageName = fmt.Sprintf("%s/kaito-%s:%s", registryName, imageName, imageTag)
+		imageName = fmt.Sprintf("%s/kaito-%s:%s", registryName, string(workspaceObj.Inference.Preset.Name), presetObj.Tag)
+	}
 
-		return imageName, imagePullSecretRefs
+	if len(workspaceObj.RegistryCredentials) > 0 {
+		creds, err := registry.ResolveCredentials(ctx, kubeClient, workspaceObj.Namespace, workspaceObj.RegistryCredentials)
+		if err != nil {
+			return "", nil, err
+		}
+		autoRefs, err := registry.EnsureImagePullSecrets(ctx, kubeClient, workspaceObj.Namespace, creds)
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to provision image pull secrets: %w", err)
+		}
+		for _, ref := range autoRefs {
+			addSecret(ref.Name)
+		}
 	}
+
+	return imageName, imagePullSecretRefs, nil
 }
 
 func CreatePresetInference(ctx context.Context, workspaceObj *kaitov1alpha1.Workspace, revisionNum string,
 	inferenceObj *model.PresetParam, supportDistributedInference bool, kubeClient client.Client) (client.Object, error) {
+	skuNumGPUs, err := utils.GetSKUNumGPUs(ctx, kubeClient, workspaceObj.Status.WorkerNodes,
+		workspaceObj.Resource.InstanceType, inferenceObj.GPUCountRequirement)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get SKU num GPUs: %v", err)
+	}
+
 	if inferenceObj.TorchRunParams != nil && supportDistributedInference {
-		if err := updateTorchParamsForDistributedInference(ctx, kubeClient, workspaceObj, inferenceObj); err != nil {
-			klog.ErrorS(err, "failed to update torch params", "workspace", workspaceObj)
+		if err := updateTorchParamsForDistributedInference(workspaceObj, inferenceObj, skuNumGPUs); err != nil {
 			return nil, err
 		}
 	}
@@ -147,39 +186,104 @@ func CreatePresetInference(ctx context.Context, workspaceObj *kaitov1alpha1.Work
 		volumeMounts = append(volumeMounts, adapterVolumeMount)
 	}
 
-	skuNumGPUs, err := utils.GetSKUNumGPUs(ctx, kubeClient, workspaceObj.Status.WorkerNodes,
-		workspaceObj.Resource.InstanceType, inferenceObj.GPUCountRequirement)
+	runtimeName := inferenceObj.Runtime
+	if workspaceObj.Inference.Runtime != "" {
+		runtimeName = model.RuntimeName(workspaceObj.Inference.Runtime)
+	}
+	runtime, ok := inference.Get(runtimeName)
+	if !ok {
+		return nil, fmt.Errorf("unknown inference runtime %q", runtimeName)
+	}
+
+	commands, resourceReq := prepareInferenceParameters(ctx, runtime, inferenceObj, skuNumGPUs)
+	image, imagePullSecrets, err := GetInferenceImageInfo(ctx, kubeClient, workspaceObj, inferenceObj)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get SKU num GPUs: %v", err)
+		return nil, err
 	}
 
-	commands, resourceReq := prepareInferenceParameters(ctx, inferenceObj, skuNumGPUs)
-	image, imagePullSecrets := GetInferenceImageInfo(ctx, workspaceObj, inferenceObj)
+	runtimeVolumes, runtimeVolumeMounts := runtime.Volumes()
+	volumes = append(volumes, runtimeVolumes...)
+	volumeMounts = append(volumeMounts, runtimeVolumeMounts...)
+
+	containerPorts := []corev1.ContainerPort{{ContainerPort: runtime.ContainerPort()}}
 
 	var depObj client.Object
 	if supportDistributedInference {
-		depObj = manifests.GenerateStatefulSetManifest(ctx, workspaceObj, image, imagePullSecrets, *workspaceObj.Resource.Count, commands,
-			containerPorts, livenessProbe, readinessProbe, resourceReq, tolerations, volumes, volumeMounts)
+		if workersPerLeader := workersPerLeader(workspaceObj); workersPerLeader > 0 {
+			lwsManifests := manifests.GenerateLeaderWorkerSetManifests(workspaceObj, image, imagePullSecrets, leaderCount(workspaceObj), workersPerLeader,
+				commands, containerPorts, runtime.LivenessProbe(), runtime.ReadinessProbe(), resourceReq, tolerations, volumes, volumeMounts)
+			for _, svc := range lwsManifests.HeadlessSvcs {
+				if err := resources.CreateResource(ctx, svc, kubeClient); err != nil {
+					return nil, err
+				}
+			}
+			for _, workerSts := range lwsManifests.Workers {
+				if err := resources.CreateResource(ctx, workerSts, kubeClient); err != nil {
+					return nil, err
+				}
+			}
+			depObj = lwsManifests.Leader
+		} else {
+			depObj = manifests.GenerateStatefulSetManifest(ctx, workspaceObj, image, imagePullSecrets, *workspaceObj.Resource.Count, commands,
+				containerPorts, runtime.LivenessProbe(), runtime.ReadinessProbe(), resourceReq, tolerations, volumes, volumeMounts)
+		}
 	} else {
 		depObj = manifests.GenerateDeploymentManifest(ctx, workspaceObj, revisionNum, image, imagePullSecrets, *workspaceObj.Resource.Count, commands,
-			containerPorts, livenessProbe, readinessProbe, resourceReq, tolerations, volumes, volumeMounts)
+			containerPorts, runtime.LivenessProbe(), runtime.ReadinessProbe(), resourceReq, tolerations, volumes, volumeMounts)
 	}
-	err = resources.CreateResource(ctx, depObj, kubeClient)
-	if client.IgnoreAlreadyExists(err) != nil {
+	if err := resources.CreateResource(ctx, depObj, kubeClient); err != nil {
 		return nil, err
 	}
 	return depObj, nil
 }
 
-// prepareInferenceParameters builds a PyTorch command:
-// torchrun <TORCH_PARAMS> <OPTIONAL_RDZV_PARAMS> baseCommand <MODEL_PARAMS>
-// and sets the GPU resources required for inference.
+// skuGPUCount parses skuNumGPUs, the SKU's actual GPU count, falling back to
+// 1 if it's missing or malformed so every caller gets a usable divisor.
+func skuGPUCount(skuNumGPUs string) int {
+	n, err := strconv.Atoi(skuNumGPUs)
+	if err != nil || n <= 0 {
+		return 1
+	}
+	return n
+}
+
+// gpusPerNode returns how many GPUs a single node/pod should actually use,
+// derived from the SKU's GPU count and capped at worldSize so a preset
+// requesting fewer total GPUs than one node has never over-subscribes a
+// single rank.
+func gpusPerNode(skuNumGPUs string, worldSize int) int {
+	n := skuGPUCount(skuNumGPUs)
+	if worldSize > 0 && worldSize < n {
+		return worldSize
+	}
+	return n
+}
+
+// prepareInferenceParameters delegates command construction to the selected
+// inference.Runtime and sets the GPU resources required for inference.
 // Returns the command and resource configuration.
-func prepareInferenceParameters(ctx context.Context, inferenceObj *model.PresetParam, skuNumGPUs string) ([]string, corev1.ResourceRequirements) {
-	torchCommand := utils.BuildCmdStr(inferenceObj.BaseCommand, inferenceObj.TorchRunParams)
-	torchCommand = utils.BuildCmdStr(torchCommand, inferenceObj.TorchRunRdzvParams)
-	modelCommand := utils.BuildCmdStr(InferenceFile, inferenceObj.ModelRunParams)
-	commands := utils.ShellCmd(torchCommand + " " + modelCommand)
+func prepareInferenceParameters(ctx context.Context, runtime inference.Runtime, inferenceObj *model.PresetParam, skuNumGPUs string) ([]string, corev1.ResourceRequirements) {
+	rawGPUs := skuGPUCount(skuNumGPUs)
+
+	worldSize := inferenceObj.WorldSize
+	if worldSize <= 0 {
+		worldSize = rawGPUs
+	}
+	// nnodes/nproc_per_node are derived from the SKU's actual GPU count so a
+	// preset requiring e.g. 8 GPUs spread over nodes with 4 GPUs each gets
+	// nnodes=2, nproc_per_node=4, instead of always collapsing to one node.
+	nnodes := (worldSize + rawGPUs - 1) / rawGPUs
+	nprocPerNode := rawGPUs
+	if worldSize < nprocPerNode {
+		nprocPerNode = worldSize
+	}
+
+	commands := runtime.BuildCommand(inference.Params{
+		Preset:       inferenceObj,
+		SKUNumGPUs:   skuNumGPUs,
+		NNodes:       nnodes,
+		NProcPerNode: nprocPerNode,
+	})
 
 	resourceRequirements := corev1.ResourceRequirements{
 		Requests: corev1.ResourceList{
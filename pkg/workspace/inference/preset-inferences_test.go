@@ -0,0 +1,117 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package inference
+
+import (
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	kaitov1alpha1 "github.com/kaito-project/kaito/api/v1alpha1"
+	"github.com/kaito-project/kaito/pkg/model"
+)
+
+func int32Ptr(i int32) *int32 { return &i }
+
+func TestUpdateTorchParamsForDistributedInferenceRankFormulaIsUnambiguous(t *testing.T) {
+	wObj := &kaitov1alpha1.Workspace{
+		ObjectMeta: metav1.ObjectMeta{Name: "llama"},
+		Resource:   kaitov1alpha1.ResourceSpec{WorkersPerLeader: int32Ptr(1)},
+	}
+	inferenceObj := &model.PresetParam{
+		WorldSize:          2,
+		TorchRunParams:     map[string]string{},
+		TorchRunRdzvParams: map[string]string{},
+	}
+
+	if err := updateTorchParamsForDistributedInference(wObj, inferenceObj, "1"); err != nil {
+		t.Fatalf("updateTorchParamsForDistributedInference returned error: %v", err)
+	}
+
+	nodeRank := inferenceObj.TorchRunParams["node_rank"]
+	// The leader never sees LWS_WORKER_INDEX (falls back to -1, +1 = 0);
+	// worker ordinal 0 sees LWS_WORKER_INDEX=0 (+1 = 1). If these evaluated
+	// to the same rank, distributed rendezvous would never converge.
+	if nodeRank == "" {
+		t.Fatal("expected node_rank to be set for a multi-node group")
+	}
+	if !containsBothFallbackAndShift(nodeRank) {
+		t.Fatalf("node_rank formula %q no longer shifts the worker ordinal away from the leader's default rank", nodeRank)
+	}
+}
+
+func TestUpdateTorchParamsForDistributedInferenceDerivesNprocFromSKUGPUCount(t *testing.T) {
+	wObj := &kaitov1alpha1.Workspace{
+		ObjectMeta: metav1.ObjectMeta{Name: "llama"},
+		Resource:   kaitov1alpha1.ResourceSpec{WorkersPerLeader: int32Ptr(1)}, // groupSize = 2
+	}
+	inferenceObj := &model.PresetParam{
+		WorldSize:          8,
+		TorchRunParams:     map[string]string{},
+		TorchRunRdzvParams: map[string]string{},
+	}
+
+	// 2 nodes of 4 GPUs each make up the required world size of 8, so this
+	// is a consistent, valid topology. The old WorldSize/groupSize formula
+	// (8/2=4) happened to agree here; it's the SKU-blind formula itself --
+	// not just this one config -- that the next test shows is unsafe.
+	if err := updateTorchParamsForDistributedInference(wObj, inferenceObj, "4"); err != nil {
+		t.Fatalf("updateTorchParamsForDistributedInference returned error: %v", err)
+	}
+
+	if got := inferenceObj.TorchRunParams["nproc_per_node"]; got != "4" {
+		t.Fatalf("nproc_per_node = %q, want 4 (the SKU's actual GPU count)", got)
+	}
+}
+
+func TestUpdateTorchParamsForDistributedInferenceRejectsGroupSizeGPUMismatch(t *testing.T) {
+	wObj := &kaitov1alpha1.Workspace{
+		ObjectMeta: metav1.ObjectMeta{Name: "llama"},
+		Resource:   kaitov1alpha1.ResourceSpec{WorkersPerLeader: int32Ptr(1)}, // groupSize = 2
+	}
+	inferenceObj := &model.PresetParam{
+		WorldSize:          8,
+		TorchRunParams:     map[string]string{},
+		TorchRunRdzvParams: map[string]string{},
+	}
+
+	// groupSize=2 nodes of 8 GPUs each would launch 16 total ranks against a
+	// world size of 8: half the ranks have no corresponding shard. The old
+	// WorldSize/groupSize formula silently under-subscribed each pod's 8
+	// requested GPUs (nproc_per_node=4) instead of catching this; it must
+	// now be rejected up front.
+	if err := updateTorchParamsForDistributedInference(wObj, inferenceObj, "8"); err == nil {
+		t.Fatal("expected an error when group size * SKU GPU count does not equal world size")
+	}
+}
+
+// containsBothFallbackAndShift is a light sanity check that the node_rank
+// expression still falls back for the leader (":--1", an out-of-band
+// sentinel distinct from any real worker ordinal) and shifts worker
+// ordinals up by one ("+ 1"), rather than re-checking exact string
+// formatting that would make this test as brittle as the bug it guards.
+func containsBothFallbackAndShift(expr string) bool {
+	return strings.Contains(expr, ":--1") && strings.Contains(expr, "+ 1")
+}
+
+func TestWorkersPerLeaderAndLeaderCountFallbacks(t *testing.T) {
+	wObj := &kaitov1alpha1.Workspace{
+		Resource: kaitov1alpha1.ResourceSpec{Count: int32Ptr(3)},
+	}
+	if got := leaderCount(wObj); got != 3 {
+		t.Fatalf("leaderCount() = %d, want fallback to Resource.Count = 3", got)
+	}
+	if got := workersPerLeader(wObj); got != 0 {
+		t.Fatalf("workersPerLeader() = %d, want 0 when unset", got)
+	}
+
+	wObj.Resource.LeaderCount = int32Ptr(5)
+	wObj.Resource.WorkersPerLeader = int32Ptr(2)
+	if got := leaderCount(wObj); got != 5 {
+		t.Fatalf("leaderCount() = %d, want explicit LeaderCount = 5", got)
+	}
+	if got := workersPerLeader(wObj); got != 2 {
+		t.Fatalf("workersPerLeader() = %d, want explicit WorkersPerLeader = 2", got)
+	}
+}
@@ -0,0 +1,184 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package manifests
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	kaitov1alpha1 "github.com/kaito-project/kaito/api/v1alpha1"
+	"github.com/kaito-project/kaito/pkg/resources/statuscheck"
+)
+
+const (
+	// LWSGroupIndexLabel marks the worker Pods of one replica group and is
+	// part of that group's worker StatefulSet and headless Service
+	// selectors. Leader Pods do not carry it.
+	LWSGroupIndexLabel = "kaito.sh/lws-group-index"
+	lwsRoleLabel       = "kaito.sh/lws-role"
+
+	// podOrdinalLabel is set by the StatefulSet controller on every Pod it
+	// creates and is the downward-API source for LWS_WORKER_INDEX.
+	podOrdinalLabel = "apps.kubernetes.io/pod-index"
+
+	// EnvLWSLeaderAddress, EnvLWSWorkerIndex, and EnvLWSGroupSize are the
+	// env vars updateTorchParamsForDistributedInference uses to compute
+	// node_rank, nnodes, and master_addr deterministically, in place of
+	// parsing $HOSTNAME.
+	EnvLWSLeaderAddress = "LWS_LEADER_ADDRESS"
+	EnvLWSWorkerIndex   = "LWS_WORKER_INDEX"
+	EnvLWSGroupSize     = "LWS_GROUP_SIZE"
+)
+
+// LeaderWorkerSetManifests is the set of objects that make up one
+// leader/worker topology: a single leader StatefulSet whose replicas are the
+// group leaders, one worker StatefulSet per group, and one headless Service
+// per group so torchrun rendezvous can resolve every rank deterministically.
+type LeaderWorkerSetManifests struct {
+	Leader       *appsv1.StatefulSet
+	Workers      []*appsv1.StatefulSet
+	HeadlessSvcs []*corev1.Service
+}
+
+// GenerateLeaderWorkerSetManifests builds the manifests for leaderCount
+// replica groups of workersPerLeader+1 pods each. For example, 4 replicas
+// of 8 GPUs spread across 2 nodes each is leaderCount=4, workersPerLeader=1.
+func GenerateLeaderWorkerSetManifests(workspaceObj *kaitov1alpha1.Workspace, image string, imagePullSecrets []corev1.LocalObjectReference,
+	leaderCount, workersPerLeader int, commands []string, containerPorts []corev1.ContainerPort,
+	livenessProbe, readinessProbe *corev1.Probe, resourceReq corev1.ResourceRequirements, tolerations []corev1.Toleration,
+	volumes []corev1.Volume, volumeMounts []corev1.VolumeMount) *LeaderWorkerSetManifests {
+
+	groupSize := workersPerLeader + 1
+	leaderName := workspaceObj.Name
+	leaderHeadlessName := fmt.Sprintf("%s-headless", workspaceObj.Name)
+
+	leaderLabels := map[string]string{"app": leaderName, lwsRoleLabel: "leader"}
+	leaderSts := &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      leaderName,
+			Namespace: workspaceObj.Namespace,
+		},
+		Spec: appsv1.StatefulSetSpec{
+			ServiceName: leaderHeadlessName,
+			Replicas:    int32Ptr(int32(leaderCount)),
+			Selector:    &metav1.LabelSelector{MatchLabels: leaderLabels},
+			Template: podTemplate(leaderName, image, imagePullSecrets, commands, containerPorts, livenessProbe, readinessProbe,
+				resourceReq, tolerations, volumes, volumeMounts, leaderLabels, map[string]string{
+					EnvLWSGroupSize: strconv.Itoa(groupSize),
+				}, false),
+		},
+	}
+
+	result := &LeaderWorkerSetManifests{Leader: leaderSts}
+
+	for i := 0; i < leaderCount; i++ {
+		groupLabels := map[string]string{"app": leaderName, LWSGroupIndexLabel: strconv.Itoa(i), lwsRoleLabel: "worker"}
+		workerName := fmt.Sprintf("%s-group-%d-worker", leaderName, i)
+		groupHeadlessName := fmt.Sprintf("%s-group-%d-headless", leaderName, i)
+		leaderAddress := fmt.Sprintf("%s-%d.%s.%s.svc.cluster.local", leaderName, i, leaderHeadlessName, workspaceObj.Namespace)
+
+		workerSts := &appsv1.StatefulSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      workerName,
+				Namespace: workspaceObj.Namespace,
+			},
+			Spec: appsv1.StatefulSetSpec{
+				ServiceName: groupHeadlessName,
+				Replicas:    int32Ptr(int32(workersPerLeader)),
+				Selector:    &metav1.LabelSelector{MatchLabels: groupLabels},
+				Template: podTemplate(workerName, image, imagePullSecrets, commands, containerPorts, livenessProbe, readinessProbe,
+					resourceReq, tolerations, volumes, volumeMounts, groupLabels, map[string]string{
+						EnvLWSLeaderAddress: leaderAddress,
+						EnvLWSGroupSize:     strconv.Itoa(groupSize),
+					}, true),
+			},
+		}
+
+		headlessSvc := &corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      groupHeadlessName,
+				Namespace: workspaceObj.Namespace,
+			},
+			Spec: corev1.ServiceSpec{
+				ClusterIP: corev1.ClusterIPNone,
+				Selector:  groupLabels,
+			},
+		}
+
+		result.Workers = append(result.Workers, workerSts)
+		result.HeadlessSvcs = append(result.HeadlessSvcs, headlessSvc)
+	}
+
+	// The leader StatefulSet's own Pod selector only ever matches leader
+	// Pods, so without these annotations statuscheck.Wait would report the
+	// workspace Ready as soon as the leaders come up, even if every worker
+	// rank is still Pending or CrashLoopBackOff.
+	if workersPerLeader > 0 && len(result.Workers) > 0 {
+		workerNames := make([]string, len(result.Workers))
+		for i, w := range result.Workers {
+			workerNames[i] = w.Name
+		}
+		svcNames := make([]string, len(result.HeadlessSvcs))
+		for i, svc := range result.HeadlessSvcs {
+			svcNames[i] = svc.Name
+		}
+		leaderSts.Annotations = map[string]string{
+			statuscheck.GroupStatefulSetsAnnotation: strings.Join(workerNames, ","),
+			statuscheck.GroupServicesAnnotation:     strings.Join(svcNames, ","),
+		}
+	}
+
+	return result
+}
+
+// podTemplate renders the Pod template shared by the leader and worker
+// StatefulSets. When withOrdinalEnv is true, LWS_WORKER_INDEX is populated
+// from the Pod's own ordinal via the downward API so node_rank tracks the
+// Pod across rescheduling instead of being derived from $HOSTNAME.
+func podTemplate(containerName, image string, imagePullSecrets []corev1.LocalObjectReference, commands []string,
+	containerPorts []corev1.ContainerPort, livenessProbe, readinessProbe *corev1.Probe, resourceReq corev1.ResourceRequirements,
+	tolerations []corev1.Toleration, volumes []corev1.Volume, volumeMounts []corev1.VolumeMount,
+	labels map[string]string, env map[string]string, withOrdinalEnv bool) corev1.PodTemplateSpec {
+
+	var envVars []corev1.EnvVar
+	for k, v := range env {
+		envVars = append(envVars, corev1.EnvVar{Name: k, Value: v})
+	}
+	if withOrdinalEnv {
+		envVars = append(envVars, corev1.EnvVar{
+			Name: EnvLWSWorkerIndex,
+			ValueFrom: &corev1.EnvVarSource{
+				FieldRef: &corev1.ObjectFieldSelector{FieldPath: fmt.Sprintf("metadata.labels['%s']", podOrdinalLabel)},
+			},
+		})
+	}
+
+	return corev1.PodTemplateSpec{
+		ObjectMeta: metav1.ObjectMeta{Labels: labels},
+		Spec: corev1.PodSpec{
+			ImagePullSecrets: imagePullSecrets,
+			Tolerations:      tolerations,
+			Containers: []corev1.Container{
+				{
+					Name:           containerName,
+					Image:          image,
+					Command:        commands,
+					Ports:          containerPorts,
+					Env:            envVars,
+					LivenessProbe:  livenessProbe,
+					ReadinessProbe: readinessProbe,
+					Resources:      resourceReq,
+					VolumeMounts:   volumeMounts,
+				},
+			},
+			Volumes: volumes,
+		},
+	}
+}
+
+func int32Ptr(i int32) *int32 { return &i }
@@ -0,0 +1,59 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package manifests
+
+import (
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	kaitov1alpha1 "github.com/kaito-project/kaito/api/v1alpha1"
+	"github.com/kaito-project/kaito/pkg/resources/statuscheck"
+)
+
+func TestGenerateLeaderWorkerSetManifestsAnnotatesLeaderWithGroupMembers(t *testing.T) {
+	workspaceObj := &kaitov1alpha1.Workspace{
+		ObjectMeta: metav1.ObjectMeta{Name: "llama", Namespace: "default"},
+	}
+
+	result := GenerateLeaderWorkerSetManifests(workspaceObj, "image:tag", nil, 2, 1,
+		[]string{"torchrun"}, nil, nil, nil, corev1.ResourceRequirements{}, nil, nil, nil)
+
+	if len(result.Workers) != 2 || len(result.HeadlessSvcs) != 2 {
+		t.Fatalf("expected 2 worker statefulsets and 2 headless services, got %d/%d", len(result.Workers), len(result.HeadlessSvcs))
+	}
+
+	wantWorkerNames := make([]string, len(result.Workers))
+	for i, w := range result.Workers {
+		wantWorkerNames[i] = w.Name
+	}
+	wantSvcNames := make([]string, len(result.HeadlessSvcs))
+	for i, svc := range result.HeadlessSvcs {
+		wantSvcNames[i] = svc.Name
+	}
+
+	gotWorkers := strings.Split(result.Leader.Annotations[statuscheck.GroupStatefulSetsAnnotation], ",")
+	gotSvcs := strings.Split(result.Leader.Annotations[statuscheck.GroupServicesAnnotation], ",")
+
+	if strings.Join(gotWorkers, ",") != strings.Join(wantWorkerNames, ",") {
+		t.Fatalf("leader GroupStatefulSetsAnnotation = %v, want %v", gotWorkers, wantWorkerNames)
+	}
+	if strings.Join(gotSvcs, ",") != strings.Join(wantSvcNames, ",") {
+		t.Fatalf("leader GroupServicesAnnotation = %v, want %v", gotSvcs, wantSvcNames)
+	}
+}
+
+func TestGenerateLeaderWorkerSetManifestsNoAnnotationWithoutWorkers(t *testing.T) {
+	workspaceObj := &kaitov1alpha1.Workspace{
+		ObjectMeta: metav1.ObjectMeta{Name: "llama", Namespace: "default"},
+	}
+
+	result := GenerateLeaderWorkerSetManifests(workspaceObj, "image:tag", nil, 1, 0,
+		[]string{"torchrun"}, nil, nil, nil, corev1.ResourceRequirements{}, nil, nil, nil)
+
+	if _, ok := result.Leader.Annotations[statuscheck.GroupStatefulSetsAnnotation]; ok {
+		t.Fatal("expected no GroupStatefulSetsAnnotation when there are no workers")
+	}
+}